@@ -0,0 +1,350 @@
+package main
+
+import (
+	"balance/internal/client"
+	"balance/internal/domain/models"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const usage = `Usage: balancectl [flags] <command> [command flags] [args]
+
+Commands:
+  balance <user_id>                       show the balance
+  account <user_id>                       show the account type and owner
+  create-account -type <type> [-owner <ref>] <user_id>
+                                          create a user, merchant or system account
+  history [-from] [-to] <user_id>         list balance changes
+  propose -reason <text> [-attachment <ref>] <user_id> <value>
+                                          propose a credit (value > 0) or debit (value < 0)
+  approve <id>                            approve and post an adjustment proposed by another operator
+  reject [-comment <text>] <id>           reject or withdraw a pending adjustment
+  adjustment <id>                         show an adjustment with its audit trail
+  adjustments [-user-id] [-status]        list adjustments
+  freeze <user_id>                        block all balance changes
+  unfreeze <user_id>                      allow balance changes again
+  reconcile                               compare balances with history, exits 2 on mismatches
+  statement [-from] [-to] [-format] [-out] <user_id>
+                                          export a statement (csv, xlsx, camt053)
+
+Flags:
+`
+
+// exitMismatch is returned by reconcile when balances disagree with history,
+// so the command can be used in cron jobs and alerts.
+const exitMismatch = 2
+
+type command struct {
+	client *client.Client
+	output string
+	stdout io.Writer
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("balancectl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", envOr("BALANCE_ADDR", "http://localhost:3000"), "service address (BALANCE_ADDR)")
+	apiKey := flags.String("api-key", os.Getenv("BALANCE_API_KEY"), "API key with the admin scope (BALANCE_API_KEY)")
+	tenant := flags.String("tenant", os.Getenv("BALANCE_TENANT"), "tenant to operate on (BALANCE_TENANT)")
+	output := flags.String("o", "table", "output format: table or json")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *output)
+		return 1
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	c := &command{
+		client: client.New(client.Options{
+			BaseURL:    *addr,
+			ApiKey:     *apiKey,
+			Tenant:     *tenant,
+			HTTPClient: &http.Client{Timeout: *timeout},
+		}),
+		output: *output,
+		stdout: stdout,
+	}
+
+	name, rest := flags.Arg(0), flags.Args()[1:]
+	handlers := map[string]func(context.Context, *flag.FlagSet, []string) (int, error){
+		"balance":        c.balance,
+		"account":        c.account,
+		"create-account": c.createAccount,
+		"history":        c.history,
+		"propose":        c.propose,
+		"approve":        c.approve,
+		"reject":         c.reject,
+		"adjustment":     c.adjustment,
+		"adjustments":    c.adjustments,
+		"freeze":         c.freeze,
+		"unfreeze":       c.unfreeze,
+		"reconcile":      c.reconcile,
+		"statement":      c.statement,
+	}
+	handler, ok := handlers[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", name)
+		flags.Usage()
+		return 1
+	}
+
+	commandFlags := flag.NewFlagSet(name, flag.ContinueOnError)
+	commandFlags.SetOutput(stderr)
+	code, err := handler(ctx, commandFlags, rest)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return code
+}
+
+func (c *command) balance(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	userId, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := c.client.GetBalance(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(balance, printBalance)
+}
+
+func (c *command) account(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	id, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	account, err := c.client.GetAccount(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(account, printAccount)
+}
+
+func (c *command) createAccount(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	accountType := flags.String("type", "", "account type: user, merchant or system")
+	owner := flags.String("owner", "", "reference to the owner, e.g. a merchant id")
+	id, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	account, err := c.client.CreateAccount(ctx, id, models.AccountType(*accountType), *owner)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(account, printAccount)
+}
+
+func (c *command) history(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	from, to := periodFlags(flags)
+	userId, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	history, err := c.client.GetHistory(ctx, userId, time.Time(*from), time.Time(*to))
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(history, printHistory)
+}
+
+func (c *command) propose(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	reason := flags.String("reason", "", "why the balance is adjusted, stored in the audit log")
+	attachment := flags.String("attachment", "", "reference to the supporting document, e.g. a ticket URL")
+	userId, err := parseIdArgs(flags, args, "user_id", 1)
+	if err != nil {
+		return 0, err
+	}
+	value, err := parseDecimal(flags.Arg(1))
+	if err != nil {
+		return 0, err
+	}
+	adjustment, err := c.client.Propose(ctx, userId, value, *reason, *attachment)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(adjustment, printAdjustment)
+}
+
+func (c *command) approve(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	id, err := parseIdArgs(flags, args, "id", 0)
+	if err != nil {
+		return 0, err
+	}
+	adjustment, err := c.client.Approve(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(adjustment, printAdjustment)
+}
+
+func (c *command) reject(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	comment := flags.String("comment", "", "why the adjustment is rejected")
+	id, err := parseIdArgs(flags, args, "id", 0)
+	if err != nil {
+		return 0, err
+	}
+	adjustment, err := c.client.Reject(ctx, id, *comment)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(adjustment, printAdjustment)
+}
+
+func (c *command) adjustment(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	id, err := parseIdArgs(flags, args, "id", 0)
+	if err != nil {
+		return 0, err
+	}
+	adjustment, err := c.client.GetAdjustment(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(adjustment, printAdjustmentEvents)
+}
+
+func (c *command) adjustments(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	userId := flags.Int64("user-id", 0, "only adjustments of the user")
+	status := flags.String("status", "", "only adjustments in the status, e.g. pending")
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+	if flags.NArg() != 0 {
+		return 0, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	adjustments, err := c.client.ListAdjustments(ctx, *userId, *status)
+	if err != nil {
+		return 0, err
+	}
+	return 0, c.print(adjustments, printAdjustments)
+}
+
+func (c *command) freeze(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	userId, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.client.Freeze(ctx, userId); err != nil {
+		return 0, err
+	}
+	return c.balance(ctx, flag.NewFlagSet("balance", flag.ContinueOnError), args)
+}
+
+func (c *command) unfreeze(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	userId, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.client.Unfreeze(ctx, userId); err != nil {
+		return 0, err
+	}
+	return c.balance(ctx, flag.NewFlagSet("balance", flag.ContinueOnError), args)
+}
+
+func (c *command) reconcile(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+	if flags.NArg() != 0 {
+		return 0, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	reconciliation, err := c.client.Reconcile(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.print(reconciliation, printReconciliation); err != nil {
+		return 0, err
+	}
+	if len(reconciliation.Mismatches) > 0 {
+		return exitMismatch, nil
+	}
+	return 0, nil
+}
+
+func (c *command) statement(ctx context.Context, flags *flag.FlagSet, args []string) (int, error) {
+	from, to := periodFlags(flags)
+	format := flags.String("format", "csv", "statement format: csv, xlsx or camt053")
+	out := flags.String("out", "", "write to the file instead of stdout")
+	userId, err := parseIdArgs(flags, args, "user_id", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if *out == "" {
+		return 0, c.client.Statement(ctx, userId, time.Time(*from), time.Time(*to), *format, c.stdout)
+	}
+
+	// write next to the target and rename, so a failed export does not leave a
+	// truncated file behind
+	file, err := os.CreateTemp(filepath.Dir(*out), ".statement-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	err = c.client.Statement(ctx, userId, time.Time(*from), time.Time(*to), *format, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, os.Rename(file.Name(), *out)
+}
+
+// parseIdArgs parses the command flags and the leading id argument followed by
+// exactly extra more arguments.
+func parseIdArgs(flags *flag.FlagSet, args []string, name string, extra int) (int64, error) {
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+	if flags.NArg() != extra+1 {
+		return 0, fmt.Errorf("expected %d argument(s), got %d", extra+1, flags.NArg())
+	}
+	id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("incorrect %s %q", name, flags.Arg(0))
+	}
+	return id, nil
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
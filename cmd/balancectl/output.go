@@ -0,0 +1,164 @@
+package main
+
+import (
+	"balance/internal/client"
+	"balance/internal/domain/models"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// timeFlag accepts RFC 3339 timestamps and plain dates.
+type timeFlag time.Time
+
+func (f *timeFlag) String() string {
+	if time.Time(*f).IsZero() {
+		return ""
+	}
+	return time.Time(*f).Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(value string) error {
+	t, _, err := parseFlagTime(value)
+	if err != nil {
+		return err
+	}
+	*f = timeFlag(t)
+	return nil
+}
+
+// endFlag is the exclusive end of a period, a plain date includes that day.
+type endFlag time.Time
+
+func (f *endFlag) String() string {
+	return (*timeFlag)(f).String()
+}
+
+func (f *endFlag) Set(value string) error {
+	t, date, err := parseFlagTime(value)
+	if err != nil {
+		return err
+	}
+	if date {
+		t = t.AddDate(0, 0, 1)
+	}
+	*f = endFlag(t)
+	return nil
+}
+
+func parseFlagTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD date")
+}
+
+func periodFlags(flags *flag.FlagSet) (*timeFlag, *endFlag) {
+	from, to := new(timeFlag), new(endFlag)
+	flags.Var(from, "from", "period start, inclusive (RFC 3339 or YYYY-MM-DD)")
+	flags.Var(to, "to", "period end, exclusive for a time, inclusive for a date (RFC 3339 or YYYY-MM-DD)")
+	return from, to
+}
+
+func parseDecimal(value string) (decimal.Decimal, error) {
+	parsed, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("incorrect value %q", value)
+	}
+	return parsed, nil
+}
+
+// print writes v as indented JSON or hands it to the table printer.
+func (c *command) print(v interface{}, table func(io.Writer, interface{})) error {
+	if c.output == "json" {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	table(w, v)
+	return w.Flush()
+}
+
+func printBalance(w io.Writer, v interface{}) {
+	balance := v.(models.Balance)
+	fmt.Fprintln(w, "USER_ID\tBALANCE\tFROZEN")
+	fmt.Fprintf(w, "%d\t%s\t%t\n", balance.UserId, balance.Value, balance.Frozen)
+}
+
+func printAccount(w io.Writer, v interface{}) {
+	account := v.(models.Account)
+	fmt.Fprintln(w, "USER_ID\tTYPE\tOWNER\tCREATED_AT")
+	fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", account.Id, account.Type, optional(account.OwnerRef),
+		account.CreatedAt.Format(time.RFC3339))
+}
+
+func printHistory(w io.Writer, v interface{}) {
+	fmt.Fprintln(w, "ID\tOCCURRED_AT\tFROM\tTO\tVALUE\tDESCRIPTION")
+	for _, entry := range v.([]client.HistoryEntry) {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.Id, entry.OccurredAt.Format(time.RFC3339),
+			optionalUser(entry.UserIdFrom), optionalUser(entry.UserIdTo), entry.Value, entry.Description)
+	}
+}
+
+func printAdjustment(w io.Writer, v interface{}) {
+	printAdjustments(w, []models.Adjustment{v.(models.Adjustment)})
+}
+
+func printAdjustments(w io.Writer, v interface{}) {
+	fmt.Fprintln(w, "ID\tCREATED_AT\tUSER_ID\tVALUE\tSTATUS\tPROPOSED_BY\tDECIDED_BY\tEXPIRES_AT\tREASON")
+	for _, adjustment := range v.([]models.Adjustment) {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", adjustment.Id,
+			adjustment.CreatedAt.Format(time.RFC3339), adjustment.UserId, adjustment.Value, adjustment.Status,
+			adjustment.ProposedBy, optional(adjustment.DecidedBy), adjustment.ExpiresAt.Format(time.RFC3339),
+			adjustment.Reason)
+	}
+}
+
+func printAdjustmentEvents(w io.Writer, v interface{}) {
+	adjustment := v.(models.Adjustment)
+	printAdjustments(w, []models.Adjustment{adjustment})
+	if adjustment.AttachmentRef != "" {
+		fmt.Fprintf(w, "\nattachment: %s\n", adjustment.AttachmentRef)
+	}
+	fmt.Fprintln(w, "\nAT\tACTION\tCLIENT\tCOMMENT")
+	for _, event := range adjustment.Events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", event.CreatedAt.Format(time.RFC3339), event.Action,
+			optional(event.Client), event.Comment)
+	}
+}
+
+func printReconciliation(w io.Writer, v interface{}) {
+	reconciliation := v.(models.Reconciliation)
+	fmt.Fprintf(w, "accounts checked: %d, mismatches: %d\n", reconciliation.Accounts,
+		len(reconciliation.Mismatches))
+	if len(reconciliation.Mismatches) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "USER_ID\tBALANCE\tHISTORY_BALANCE\tDIFFERENCE")
+	for _, mismatch := range reconciliation.Mismatches {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", mismatch.UserId, mismatch.Balance, mismatch.HistoryBalance,
+			mismatch.Balance.Sub(mismatch.HistoryBalance))
+	}
+}
+
+func optionalUser(userId int64) string {
+	if userId == 0 {
+		return "-"
+	}
+	return fmt.Sprint(userId)
+}
+
+func optional(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"balance/internal/application"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,7 +17,17 @@ func main() {
 
 	app := application.App{}
 
-	go application.Start(ctx, &app)
-	<-ctx.Done()
-	application.Stop(&app)
+	err := application.Start(ctx, &app, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "application start failed: %v\n", err)
+	} else {
+		err = application.Wait(ctx, &app)
+	}
+
+	if stopErr := application.Stop(&app); stopErr != nil || err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,12 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS balance.outbox
+(
+    id           bigserial PRIMARY KEY,
+    event_type   text        NOT NULL,
+    payload      jsonb       NOT NULL,
+    created_at   timestamptz NOT NULL,
+    delivered_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_undelivered_idx ON balance.outbox (id) WHERE delivered_at IS NULL;
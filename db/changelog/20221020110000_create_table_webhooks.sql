@@ -0,0 +1,25 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS balance.webhook_subscriptions
+(
+    id          bigserial PRIMARY KEY,
+    url         text        NOT NULL,
+    secret      text        NOT NULL,
+    event_types text[]      NOT NULL DEFAULT '{}',
+    user_id     bigint,
+    created_at  timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS balance.webhook_dead_letters
+(
+    id              bigserial PRIMARY KEY,
+    subscription_id bigint      NOT NULL REFERENCES balance.webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        bigint      NOT NULL,
+    event_type      text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    created_at      timestamptz NOT NULL,
+    attempts        int         NOT NULL,
+    last_error      text        NOT NULL,
+    failed_at       timestamptz NOT NULL,
+    replayed_at     timestamptz
+);
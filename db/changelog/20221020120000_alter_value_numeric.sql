@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE balance.balance ALTER COLUMN value TYPE numeric;
+
+ALTER TABLE balance.history ALTER COLUMN value TYPE numeric;
+
+-- +goose Down
+
+ALTER TABLE balance.history ALTER COLUMN value TYPE decimal(10, 2);
+
+ALTER TABLE balance.balance ALTER COLUMN value TYPE decimal(10, 2);
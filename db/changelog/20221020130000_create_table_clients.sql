@@ -0,0 +1,18 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS balance.clients
+(
+    id         bigserial PRIMARY KEY,
+    name       text        NOT NULL UNIQUE,
+    key_hash   text        NOT NULL UNIQUE,
+    scopes     text[]      NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL
+);
+
+ALTER TABLE balance.history ADD COLUMN IF NOT EXISTS client text;
+
+-- +goose Down
+
+ALTER TABLE balance.history DROP COLUMN IF EXISTS client;
+
+DROP TABLE IF EXISTS balance.clients;
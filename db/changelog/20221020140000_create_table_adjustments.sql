@@ -0,0 +1,21 @@
+-- +goose Up
+
+ALTER TABLE balance.balance ADD COLUMN IF NOT EXISTS frozen boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS balance.adjustments
+(
+    id         bigserial PRIMARY KEY,
+    user_id    bigint      NOT NULL,
+    value      numeric     NOT NULL,
+    reason     text        NOT NULL,
+    client     text,
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS adjustments_user_id_idx ON balance.adjustments (user_id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS balance.adjustments;
+
+ALTER TABLE balance.balance DROP COLUMN IF EXISTS frozen;
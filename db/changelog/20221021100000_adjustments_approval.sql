@@ -0,0 +1,51 @@
+-- +goose Up
+
+ALTER TABLE balance.adjustments RENAME COLUMN client TO proposed_by;
+
+-- adjustments made before maker-checker were posted immediately
+ALTER TABLE balance.adjustments
+    ADD COLUMN IF NOT EXISTS status         text NOT NULL DEFAULT 'posted',
+    ADD COLUMN IF NOT EXISTS attachment_ref text,
+    ADD COLUMN IF NOT EXISTS decided_by     text,
+    ADD COLUMN IF NOT EXISTS decided_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS expires_at     timestamptz;
+
+UPDATE balance.adjustments SET expires_at = created_at, decided_by = proposed_by, decided_at = created_at;
+
+ALTER TABLE balance.adjustments
+    ALTER COLUMN status SET DEFAULT 'pending',
+    ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS adjustments_pending_expires_at_idx
+    ON balance.adjustments (expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS balance.adjustment_events
+(
+    id            bigserial PRIMARY KEY,
+    adjustment_id bigint      NOT NULL REFERENCES balance.adjustments (id),
+    action        text        NOT NULL,
+    client        text,
+    comment       text,
+    created_at    timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS adjustment_events_adjustment_id_idx ON balance.adjustment_events (adjustment_id);
+
+INSERT INTO balance.adjustment_events (adjustment_id, action, client, comment, created_at)
+SELECT id, 'posted', proposed_by, 'posted without approval', created_at
+FROM balance.adjustments;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS balance.adjustment_events;
+
+DROP INDEX IF EXISTS balance.adjustments_pending_expires_at_idx;
+
+ALTER TABLE balance.adjustments
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS attachment_ref,
+    DROP COLUMN IF EXISTS decided_by,
+    DROP COLUMN IF EXISTS decided_at,
+    DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE balance.adjustments RENAME COLUMN proposed_by TO client;
@@ -0,0 +1,56 @@
+-- +goose Up
+
+-- rows created before multi-tenancy belong to the default tenant
+ALTER TABLE balance.balance ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.history ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.outbox ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.webhook_subscriptions ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.webhook_dead_letters ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.adjustments ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+ALTER TABLE balance.adjustment_events ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+
+ALTER TABLE balance.balance ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.history ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.outbox ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.webhook_subscriptions ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.webhook_dead_letters ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.adjustments ALTER COLUMN tenant DROP DEFAULT;
+ALTER TABLE balance.adjustment_events ALTER COLUMN tenant DROP DEFAULT;
+
+-- the same user_id may exist in several tenants
+ALTER TABLE balance.balance DROP CONSTRAINT IF EXISTS balance_pkey;
+ALTER TABLE balance.balance ADD PRIMARY KEY (tenant, user_id);
+
+CREATE INDEX IF NOT EXISTS history_tenant_from_id_idx ON balance.history (tenant, from_id);
+CREATE INDEX IF NOT EXISTS history_tenant_to_id_idx ON balance.history (tenant, to_id);
+
+DROP INDEX IF EXISTS balance.adjustments_user_id_idx;
+CREATE INDEX IF NOT EXISTS adjustments_tenant_user_id_idx ON balance.adjustments (tenant, user_id);
+
+-- clients without a tenant are platform clients choosing the tenant per request
+ALTER TABLE balance.clients ADD COLUMN IF NOT EXISTS tenant text;
+ALTER TABLE balance.clients DROP CONSTRAINT IF EXISTS clients_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS clients_tenant_name_idx ON balance.clients (COALESCE(tenant, ''), name);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS balance.clients_tenant_name_idx;
+ALTER TABLE balance.clients ADD CONSTRAINT clients_name_key UNIQUE (name);
+ALTER TABLE balance.clients DROP COLUMN IF EXISTS tenant;
+
+DROP INDEX IF EXISTS balance.adjustments_tenant_user_id_idx;
+CREATE INDEX IF NOT EXISTS adjustments_user_id_idx ON balance.adjustments (user_id);
+
+DROP INDEX IF EXISTS balance.history_tenant_from_id_idx;
+DROP INDEX IF EXISTS balance.history_tenant_to_id_idx;
+
+ALTER TABLE balance.balance DROP CONSTRAINT IF EXISTS balance_pkey;
+ALTER TABLE balance.balance ADD PRIMARY KEY (user_id);
+
+ALTER TABLE balance.adjustment_events DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.adjustments DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.webhook_dead_letters DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.webhook_subscriptions DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.outbox DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.history DROP COLUMN IF EXISTS tenant;
+ALTER TABLE balance.balance DROP COLUMN IF EXISTS tenant;
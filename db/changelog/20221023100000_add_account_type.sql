@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- every existing balance is a user account, created with its first credit
+ALTER TABLE balance.balance
+    ADD COLUMN IF NOT EXISTS type       text NOT NULL DEFAULT 'user' CHECK (type IN ('user', 'merchant', 'system')),
+    ADD COLUMN IF NOT EXISTS owner_ref  text,
+    ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+UPDATE balance.balance b
+SET created_at = h.first_credit
+FROM (
+    SELECT tenant, to_id, MIN(occurred_at) AS first_credit FROM balance.history GROUP BY tenant, to_id
+) h
+WHERE h.tenant = b.tenant AND h.to_id = b.user_id;
+
+-- +goose Down
+
+ALTER TABLE balance.balance
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS owner_ref,
+    DROP COLUMN IF EXISTS type;
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- events queued for a subscription until they are delivered or dead lettered
+CREATE TABLE IF NOT EXISTS balance.webhook_deliveries
+(
+    id              bigserial PRIMARY KEY,
+    tenant          text        NOT NULL,
+    subscription_id bigint      NOT NULL REFERENCES balance.webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        bigint      NOT NULL,
+    event_type      text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    created_at      timestamptz NOT NULL,
+    attempts        int         NOT NULL DEFAULT 0,
+    last_error      text,
+    next_attempt_at timestamptz NOT NULL,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at_idx ON balance.webhook_deliveries (next_attempt_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS balance.webhook_deliveries;
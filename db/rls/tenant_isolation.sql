@@ -0,0 +1,50 @@
+-- Optional row-level security for roles other than the service owner, e.g.
+-- reporting or support roles with direct database access. Such a role sees
+-- only the rows of the tenant set in its session:
+--
+--     SET balance.tenant = 'acme';
+--
+-- The service connects as the table owner, which is not subject to these
+-- policies, and enforces the isolation in its queries. Apply with psql after
+-- the migrations.
+
+ALTER TABLE balance.balance ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.history ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.webhook_subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.webhook_dead_letters ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.adjustments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.adjustment_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance.clients ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.balance;
+CREATE POLICY tenant_isolation ON balance.balance
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.history;
+CREATE POLICY tenant_isolation ON balance.history
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.outbox;
+CREATE POLICY tenant_isolation ON balance.outbox
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.webhook_subscriptions;
+CREATE POLICY tenant_isolation ON balance.webhook_subscriptions
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.webhook_dead_letters;
+CREATE POLICY tenant_isolation ON balance.webhook_dead_letters
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.adjustments;
+CREATE POLICY tenant_isolation ON balance.adjustments
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.adjustment_events;
+CREATE POLICY tenant_isolation ON balance.adjustment_events
+    USING (tenant = current_setting('balance.tenant', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON balance.clients;
+CREATE POLICY tenant_isolation ON balance.clients
+    USING (tenant = current_setting('balance.tenant', true));
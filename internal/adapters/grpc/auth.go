@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"balance/internal/domain/auth"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+const (
+	apiKeyMetadata        = "x-api-key"
+	tenantMetadata        = "x-tenant-id"
+	authorizationMetadata = "authorization"
+	bearerPrefix          = "Bearer "
+)
+
+var methodScopes = map[string]string{
+	"/balance.v1.Balance/AddIncome":  models.ScopeIncome,
+	"/balance.v1.Balance/AddExpense": models.ScopeExpense,
+	"/balance.v1.Balance/DoTransfer": models.ScopeTransfer,
+	"/balance.v1.Balance/GetBalance": models.ScopeBalance,
+}
+
+func (s *Server) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	var bound []string
+	apiKey := incomingMetadata(ctx, apiKeyMetadata)
+	if s.auth != nil && (apiKey != "" || s.tokens == nil) {
+		client, err := s.auth.Authenticate(ctx, apiKey)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		ctx = auth.WithClient(ctx, client)
+		bound = append(bound, client.Tenant)
+	}
+
+	authorization := incomingMetadata(ctx, authorizationMetadata)
+	if len(authorization) > len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) &&
+		s.tokens != nil {
+		userId, userTenant, err := s.tokens.VerifyToken(ctx, authorization[len(bearerPrefix):])
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		ctx = auth.WithUser(ctx, userId)
+		bound = append(bound, userTenant)
+		if _, ok := auth.ClientFromContext(ctx); !ok {
+			ctx = auth.WithClient(ctx, auth.UserClient(userId, userTenant))
+		}
+	}
+
+	resolved, err := s.tenants.Resolve(incomingMetadata(ctx, tenantMetadata), bound...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	ctx = tenant.WithTenant(ctx, resolved)
+
+	client, ok := auth.ClientFromContext(ctx)
+	if !ok {
+		if s.auth == nil {
+			return handler(ctx, req)
+		}
+		return nil, toStatus(e.UnauthenticatedError)
+	}
+	scope, ok := methodScopes[info.FullMethod]
+	if !ok || !client.HasScope(scope) {
+		return nil, toStatus(e.ForbiddenError)
+	}
+	return handler(ctx, req)
+}
+
+func incomingMetadata(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
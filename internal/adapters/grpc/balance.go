@@ -0,0 +1,121 @@
+package grpc
+
+import (
+	"balance/internal/adapters/grpc/pb"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func toStatus(err error) error {
+	var validationErr *e.ValidationError
+	if errors.As(err, &validationErr) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(validationErr.Fields))
+		for _, field := range validationErr.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		st, detailsErr := status.New(codes.InvalidArgument, err.Error()).
+			WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+		if detailsErr != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return st.Err()
+	}
+
+	switch {
+	case errors.Is(err, e.UnknownUserIdError):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, e.NotEnoughUserBalanceError), errors.Is(err, e.TransferNotAllowedError),
+		errors.Is(err, e.AccountFrozenError):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, e.UnauthenticatedError):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, e.ForbiddenError):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, e.DatabaseError):
+		return status.Error(codes.Internal, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
+
+func parseValue(value string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Decimal{}, status.Errorf(codes.InvalidArgument, "incorrect value %q", value)
+	}
+	return d, nil
+}
+
+func (s *Server) AddIncome(ctx context.Context, req *pb.AddIncomeRequest) (*pb.AddIncomeResponse, error) {
+	value, err := parseValue(req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.balance.AddIncome(ctx, tenant.FromContext(ctx), models.BalanceWithDesc{
+		UserId:      req.GetUserId(),
+		Value:       value,
+		Time:        time.Now(),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.AddIncomeResponse{}, nil
+}
+
+func (s *Server) AddExpense(ctx context.Context, req *pb.AddExpenseRequest) (*pb.AddExpenseResponse, error) {
+	value, err := parseValue(req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.balance.AddExpense(ctx, tenant.FromContext(ctx), models.BalanceWithDesc{
+		UserId:      req.GetUserId(),
+		Value:       value,
+		Time:        time.Now(),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.AddExpenseResponse{}, nil
+}
+
+func (s *Server) DoTransfer(ctx context.Context, req *pb.DoTransferRequest) (*pb.DoTransferResponse, error) {
+	value, err := parseValue(req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.balance.DoTransfer(ctx, tenant.FromContext(ctx), models.Transaction{
+		UserIdFrom:  req.GetUserIdFrom(),
+		UserIdTo:    req.GetUserIdTo(),
+		Value:       value,
+		Time:        time.Now(),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DoTransferResponse{}, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+	balance, err := s.balance.GetBalance(ctx, tenant.FromContext(ctx), req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetBalanceResponse{UserId: balance.UserId, Value: balance.Value.String()}, nil
+}
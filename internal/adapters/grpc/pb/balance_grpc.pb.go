@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: api/balance/v1/balance.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// BalanceClient is the client API for Balance service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BalanceClient interface {
+	AddIncome(ctx context.Context, in *AddIncomeRequest, opts ...grpc.CallOption) (*AddIncomeResponse, error)
+	AddExpense(ctx context.Context, in *AddExpenseRequest, opts ...grpc.CallOption) (*AddExpenseResponse, error)
+	DoTransfer(ctx context.Context, in *DoTransferRequest, opts ...grpc.CallOption) (*DoTransferResponse, error)
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+}
+
+type balanceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBalanceClient(cc grpc.ClientConnInterface) BalanceClient {
+	return &balanceClient{cc}
+}
+
+func (c *balanceClient) AddIncome(ctx context.Context, in *AddIncomeRequest, opts ...grpc.CallOption) (*AddIncomeResponse, error) {
+	out := new(AddIncomeResponse)
+	err := c.cc.Invoke(ctx, "/balance.v1.Balance/AddIncome", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceClient) AddExpense(ctx context.Context, in *AddExpenseRequest, opts ...grpc.CallOption) (*AddExpenseResponse, error) {
+	out := new(AddExpenseResponse)
+	err := c.cc.Invoke(ctx, "/balance.v1.Balance/AddExpense", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceClient) DoTransfer(ctx context.Context, in *DoTransferRequest, opts ...grpc.CallOption) (*DoTransferResponse, error) {
+	out := new(DoTransferResponse)
+	err := c.cc.Invoke(ctx, "/balance.v1.Balance/DoTransfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, "/balance.v1.Balance/GetBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BalanceServer is the server API for Balance service.
+// All implementations must embed UnimplementedBalanceServer
+// for forward compatibility
+type BalanceServer interface {
+	AddIncome(context.Context, *AddIncomeRequest) (*AddIncomeResponse, error)
+	AddExpense(context.Context, *AddExpenseRequest) (*AddExpenseResponse, error)
+	DoTransfer(context.Context, *DoTransferRequest) (*DoTransferResponse, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	mustEmbedUnimplementedBalanceServer()
+}
+
+// UnimplementedBalanceServer must be embedded to have forward compatible implementations.
+type UnimplementedBalanceServer struct {
+}
+
+func (UnimplementedBalanceServer) AddIncome(context.Context, *AddIncomeRequest) (*AddIncomeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddIncome not implemented")
+}
+func (UnimplementedBalanceServer) AddExpense(context.Context, *AddExpenseRequest) (*AddExpenseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddExpense not implemented")
+}
+func (UnimplementedBalanceServer) DoTransfer(context.Context, *DoTransferRequest) (*DoTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DoTransfer not implemented")
+}
+func (UnimplementedBalanceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedBalanceServer) mustEmbedUnimplementedBalanceServer() {}
+
+// UnsafeBalanceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BalanceServer will
+// result in compilation errors.
+type UnsafeBalanceServer interface {
+	mustEmbedUnimplementedBalanceServer()
+}
+
+func RegisterBalanceServer(s grpc.ServiceRegistrar, srv BalanceServer) {
+	s.RegisterService(&Balance_ServiceDesc, srv)
+}
+
+func _Balance_AddIncome_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddIncomeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceServer).AddIncome(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/balance.v1.Balance/AddIncome",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceServer).AddIncome(ctx, req.(*AddIncomeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Balance_AddExpense_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddExpenseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceServer).AddExpense(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/balance.v1.Balance/AddExpense",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceServer).AddExpense(ctx, req.(*AddExpenseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Balance_DoTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DoTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceServer).DoTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/balance.v1.Balance/DoTransfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceServer).DoTransfer(ctx, req.(*DoTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Balance_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/balance.v1.Balance/GetBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Balance_ServiceDesc is the grpc.ServiceDesc for Balance service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Balance_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "balance.v1.Balance",
+	HandlerType: (*BalanceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddIncome",
+			Handler:    _Balance_AddIncome_Handler,
+		},
+		{
+			MethodName: "AddExpense",
+			Handler:    _Balance_AddExpense_Handler,
+		},
+		{
+			MethodName: "DoTransfer",
+			Handler:    _Balance_DoTransfer_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _Balance_GetBalance_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/balance/v1/balance.proto",
+}
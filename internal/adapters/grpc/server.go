@@ -0,0 +1,73 @@
+package grpc
+
+import (
+	"balance/internal/adapters/grpc/pb"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"net"
+)
+
+type Server struct {
+	pb.UnimplementedBalanceServer
+	balance ports.BalancePort
+	auth    ports.AuthPort
+	tokens  ports.TokenVerifierPort
+	tenants ports.TenantPort
+	server  *grpc.Server
+	logger  *zap.SugaredLogger
+}
+
+// New creates the gRPC server. A nil auth disables API key authentication,
+// a nil tokens disables end-user JWT authorization.
+func New(balance ports.BalancePort, auth ports.AuthPort, tokens ports.TokenVerifierPort,
+	logger *zap.SugaredLogger) *Server {
+	s := &Server{
+		balance: balance,
+		auth:    auth,
+		tokens:  tokens,
+		tenants: tenant.NewRegistry(models.TenantSettings{}, nil),
+		logger:  logger,
+	}
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(s.authenticate))
+	pb.RegisterBalanceServer(s.server, s)
+	return s
+}
+
+// WithTenants sets the tenants requests may address, by default only the
+// default tenant exists.
+func (s *Server) WithTenants(tenants ports.TenantPort) {
+	s.tenants = tenants
+}
+
+func (s *Server) Start(port string) error {
+	listen, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %v", port, err)
+	}
+
+	if err := s.server.Serve(listen); err != nil {
+		return fmt.Errorf("failed to serve grpc server over port %s: %v", port, err)
+	}
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/utils"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) createAccount(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	accountParams := &struct {
+		Id       int64              `json:"id"`
+		Type     models.AccountType `json:"type"`
+		OwnerRef string             `json:"owner_ref"`
+	}{}
+	err = decodeJSON(body, accountParams)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	logUser(r, accountParams.Id)
+
+	account, err := s.balance.CreateAccount(r.Context(), tenant.FromContext(r.Context()), models.Account{
+		Id:       accountParams.Id,
+		Type:     accountParams.Type,
+		OwnerRef: accountParams.OwnerRef,
+	})
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusCreated, account)
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect user_id parameter")
+		return
+	}
+	logUser(r, id)
+
+	account, err := s.balance.GetAccount(r.Context(), tenant.FromContext(r.Context()), id)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, account)
+}
+
+func (s *Server) freezeAccount(w http.ResponseWriter, r *http.Request) {
+	s.setFrozen(w, r, true)
+}
+
+func (s *Server) unfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	s.setFrozen(w, r, false)
+}
+
+func (s *Server) setFrozen(w http.ResponseWriter, r *http.Request, frozen bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect user_id parameter")
+		return
+	}
+	logUser(r, id)
+
+	err = s.balance.SetFrozen(r.Context(), tenant.FromContext(r.Context()), id, frozen)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{\"status\": \"success\"}"))
+}
+
+func (s *Server) reconcile(w http.ResponseWriter, r *http.Request) {
+	reconciliation, err := s.balance.Reconcile(r.Context(), tenant.FromContext(r.Context()))
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, reconciliation)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
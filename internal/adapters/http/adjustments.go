@@ -0,0 +1,136 @@
+package http
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"github.com/go-chi/chi"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) proposeAdjustment(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	adjustmentParams := &struct {
+		UserId        int64  `json:"user_id"`
+		Value         string `json:"value"`
+		Reason        string `json:"reason"`
+		AttachmentRef string `json:"attachment_ref"`
+	}{}
+	err = decodeJSON(body, adjustmentParams)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	adjustment := models.Adjustment{
+		UserId:        adjustmentParams.UserId,
+		Reason:        adjustmentParams.Reason,
+		AttachmentRef: adjustmentParams.AttachmentRef,
+	}
+	if err = adjustment.Value.UnmarshalText([]byte(adjustmentParams.Value)); err != nil {
+		s.writeBadRequest(w, r, "incorrect value")
+		return
+	}
+	logUser(r, adjustment.UserId)
+
+	adjustment, err = s.adjustments.Propose(r.Context(), tenant.FromContext(r.Context()), adjustment)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusCreated, adjustment)
+}
+
+func (s *Server) approveAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.adjustmentId(w, r)
+	if !ok {
+		return
+	}
+
+	adjustment, err := s.adjustments.Approve(r.Context(), tenant.FromContext(r.Context()), id)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, adjustment)
+}
+
+func (s *Server) rejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.adjustmentId(w, r)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	rejectParams := &struct {
+		Comment string `json:"comment"`
+	}{}
+	if len(body) > 0 {
+		if err = decodeJSON(body, rejectParams); err != nil {
+			s.writeBadRequest(w, r, err.Error())
+			return
+		}
+	}
+
+	adjustment, err := s.adjustments.Reject(r.Context(), tenant.FromContext(r.Context()), id, rejectParams.Comment)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, adjustment)
+}
+
+func (s *Server) getAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.adjustmentId(w, r)
+	if !ok {
+		return
+	}
+
+	adjustment, err := s.adjustments.GetAdjustment(r.Context(), tenant.FromContext(r.Context()), id)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, adjustment)
+}
+
+func (s *Server) listAdjustments(w http.ResponseWriter, r *http.Request) {
+	filter := models.AdjustmentFilter{Status: r.URL.Query().Get("status")}
+	if userId := r.URL.Query().Get("user_id"); userId != "" {
+		id, err := strconv.ParseInt(userId, 10, 64)
+		if err != nil {
+			s.writeBadRequest(w, r, "incorrect user_id parameter")
+			return
+		}
+		filter.UserId = id
+		logUser(r, id)
+	}
+
+	adjustments, err := s.adjustments.ListAdjustments(r.Context(), tenant.FromContext(r.Context()), filter)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, adjustments)
+}
+
+func (s *Server) adjustmentId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect id parameter")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"balance/internal/domain/auth"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/utils"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	tenantHeader = "X-Tenant-Id"
+	bearerPrefix = "Bearer "
+)
+
+// authenticate identifies the caller and resolves the tenant of the request
+// from the tenant the credentials are bound to and the X-Tenant-Id header.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var bound []string
+
+		apiKey := r.Header.Get(apiKeyHeader)
+		if s.auth != nil && (apiKey != "" || s.tokens == nil) {
+			client, err := s.auth.Authenticate(ctx, apiKey)
+			if err != nil {
+				s.writeProblem(w, r, err)
+				return
+			}
+			ctx = auth.WithClient(ctx, client)
+			bound = append(bound, client.Tenant)
+			logClient(r, client.Name)
+		}
+
+		if token := bearerToken(r); token != "" && s.tokens != nil {
+			userId, userTenant, err := s.tokens.VerifyToken(ctx, token)
+			if err != nil {
+				s.writeProblem(w, r, err)
+				return
+			}
+			ctx = auth.WithUser(ctx, userId)
+			bound = append(bound, userTenant)
+			logUser(r, userId)
+			if _, ok := auth.ClientFromContext(ctx); !ok {
+				ctx = auth.WithClient(ctx, auth.UserClient(userId, userTenant))
+				logClient(r, auth.UserClient(userId, userTenant).Name)
+			}
+		}
+
+		resolved, err := s.tenants.Resolve(r.Header.Get(tenantHeader), bound...)
+		if err != nil {
+			s.writeProblem(w, r, err)
+			return
+		}
+		ctx = tenant.WithTenant(ctx, resolved)
+		logTenant(r, resolved)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := auth.ClientFromContext(r.Context())
+			if !ok {
+				if s.auth == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				s.writeProblem(w, r, e.UnauthenticatedError)
+				return
+			}
+			if !client.HasScope(scope) {
+				s.writeProblem(w, r, e.ForbiddenError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return header[len(bearerPrefix):]
+	}
+	return ""
+}
+
+func (s *Server) createClient(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		s.writeBadRequest(w, r, "authentication is disabled")
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	clientParams := &struct {
+		Name   string   `json:"name"`
+		Tenant string   `json:"tenant"`
+		Scopes []string `json:"scopes"`
+	}{}
+	err = decodeJSON(body, clientParams)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+
+	if clientParams.Tenant != "" {
+		if _, err = s.tenants.Settings(clientParams.Tenant); err != nil {
+			s.writeProblem(w, r, err)
+			return
+		}
+	}
+
+	client, apiKey, err := s.auth.CreateClient(r.Context(), clientParams.Tenant, clientParams.Name,
+		clientParams.Scopes)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	response, err := json.Marshal(struct {
+		models.Client
+		ApiKey string `json:"api_key"`
+	}{Client: client, ApiKey: apiKey})
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(response)
+}
+
+func (s *Server) listClients(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		s.writeBadRequest(w, r, "authentication is disabled")
+		return
+	}
+
+	clients, err := s.auth.ListClients(r.Context(), r.URL.Query().Get("tenant"))
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	response, err := json.Marshal(clients)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -3,9 +3,9 @@ package http
 import (
 	e "balance/internal/domain/errors"
 	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/utils"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"github.com/go-chi/chi"
 	"io/ioutil"
 	"net/http"
@@ -16,148 +16,127 @@ import (
 func (s *Server) balanceHandlers() http.Handler {
 	h := chi.NewMux()
 	h.Route("/", func(r chi.Router) {
-		h.Post("/income", s.addIncome)
-		h.Post("/expense", s.addExpense)
-		h.Post("/transfer", s.doTransfer)
-		h.Get("/balance", s.getBalance)
+		h.With(s.requireScope(models.ScopeIncome), s.rateLimit).Post("/income", s.addIncome)
+		h.With(s.requireScope(models.ScopeExpense), s.rateLimit).Post("/expense", s.addExpense)
+		h.With(s.requireScope(models.ScopeTransfer), s.rateLimit).Post("/transfer", s.doTransfer)
+		h.With(s.requireScope(models.ScopeBalance), s.rateLimit).Get("/balance", s.getBalance)
+		h.With(s.requireScope(models.ScopeStatements), s.rateLimit).Get("/statements", s.getStatement)
+		h.With(s.requireScope(models.ScopeStatements), s.rateLimit).Get("/history", s.getHistory)
 	})
 	return h
 }
 
 func (s *Server) addIncome(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	incomeParams := &models.BalanceWithDesc{}
-	err = json.Unmarshal(body, incomeParams)
+	err = decodeJSON(body, incomeParams)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	incomeParams.Time = time.Now()
+	logUser(r, incomeParams.UserId)
 
-	err = s.balance.AddIncome(r.Context(), *incomeParams)
+	err = s.balance.AddIncome(r.Context(), tenant.FromContext(r.Context()), *incomeParams)
 
 	if err != nil {
-		if errors.Is(err, e.DatabaseError) {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeProblem(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("{\"status\": \"success\"}"))
 
 }
 
 func (s *Server) addExpense(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	incomeParams := &models.BalanceWithDesc{}
-	err = json.Unmarshal(body, incomeParams)
+	err = decodeJSON(body, incomeParams)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	incomeParams.Time = time.Now()
+	logUser(r, incomeParams.UserId)
 
-	err = s.balance.AddExpense(r.Context(), *incomeParams)
+	err = s.balance.AddExpense(r.Context(), tenant.FromContext(r.Context()), *incomeParams)
 
 	if err != nil {
-		if errors.Is(err, e.DatabaseError) {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeProblem(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("{\"status\": \"success\"}"))
 }
 
 func (s *Server) doTransfer(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	transferParams := &models.Transaction{}
-	err = json.Unmarshal(body, transferParams)
+	err = decodeJSON(body, transferParams)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeBadRequest(w, r, err.Error())
 		return
 	}
 	transferParams.Time = time.Now()
+	logUser(r, transferParams.UserIdFrom)
 
-	err = s.balance.DoTransfer(r.Context(), *transferParams)
+	err = s.balance.DoTransfer(r.Context(), tenant.FromContext(r.Context()), *transferParams)
 
 	if err != nil {
-		if errors.Is(err, e.DatabaseError) {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeProblem(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("{\"status\": \"success\"}"))
 }
 
 func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	idRaw := r.URL.Query().Get("user_id")
 	if idRaw == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("{\"errorText\": \"missing required user_id parameter\"}"))
+		s.writeBadRequest(w, r, "missing required user_id parameter")
 		return
 	}
 	var id int64
 	var err error
 	id, err = strconv.ParseInt(idRaw, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("{\"errorText\": \"incorrect user_id parameter\"}"))
+		s.writeBadRequest(w, r, "incorrect user_id parameter")
 		return
 	}
 
-	balance, err := s.balance.GetBalance(r.Context(), id)
+	logUser(r, id)
+
+	balance, err := s.balance.GetBalance(r.Context(), tenant.FromContext(r.Context()), id)
 
 	if err != nil {
-		if errors.Is(err, e.DatabaseError) {
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			w.WriteHeader(http.StatusBadRequest)
-		}
-		w.Write([]byte(fmt.Sprintf("{\"errorText\": \"%s\"}", err)))
+		s.writeProblem(w, r, err)
 		return
 	}
 
 	response, err := json.Marshal(balance)
 
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("{\"errorText\": \"server error\"}"))
+		utils.Logger(r.Context(), s.logger).Errorf("marshal balance fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 
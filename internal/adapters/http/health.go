@@ -0,0 +1,43 @@
+package http
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"balance/internal/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// WithHealth sets the readiness checks reported on /readyz.
+func (s *Server) WithHealth(health ports.HealthPort) {
+	s.health = health
+}
+
+func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeHealth(w, r, models.Health{Status: models.HealthOk, Components: map[string]models.ComponentHealth{}})
+}
+
+func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	health := models.Health{Status: models.HealthOk, Components: map[string]models.ComponentHealth{}}
+	if s.health != nil {
+		health = s.health.Ready(r.Context())
+	}
+	s.writeHealth(w, r, health)
+}
+
+func (s *Server) writeHealth(w http.ResponseWriter, r *http.Request, health models.Health) {
+	response, err := json.Marshal(health)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal health fail: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if health.Status != models.HealthOk {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/tenant"
+	"balance/internal/utils"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type historyEntry struct {
+	Id          int64           `json:"id"`
+	UserIdFrom  int64           `json:"user_id_from"`
+	UserIdTo    int64           `json:"user_id_to"`
+	Value       decimal.Decimal `json:"value"`
+	Description string          `json:"description"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+func (s *Server) getHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	idRaw := query.Get("user_id")
+	if idRaw == "" {
+		s.writeBadRequest(w, r, "missing required user_id parameter")
+		return
+	}
+	id, err := strconv.ParseInt(idRaw, 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect user_id parameter")
+		return
+	}
+	logUser(r, id)
+
+	from := time.Time{}
+	if raw := query.Get("from"); raw != "" {
+		from, err = parseStatementTime(raw)
+		if err != nil {
+			s.writeBadRequest(w, r, "incorrect from parameter")
+			return
+		}
+	}
+	to := time.Now()
+	if raw := query.Get("to"); raw != "" {
+		to, err = parseStatementEnd(raw)
+		if err != nil {
+			s.writeBadRequest(w, r, "incorrect to parameter")
+			return
+		}
+	}
+
+	history, err := s.balance.GetHistory(r.Context(), tenant.FromContext(r.Context()), id, from, to)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	entries := make([]historyEntry, 0, len(history))
+	for _, transaction := range history {
+		entries = append(entries, historyEntry{
+			Id:          transaction.Id,
+			UserIdFrom:  transaction.UserIdFrom,
+			UserIdTo:    transaction.UserIdTo,
+			Value:       transaction.Value,
+			Description: transaction.Description,
+			OccurredAt:  transaction.Time,
+		})
+	}
+
+	response, err := json.Marshal(entries)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal history fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,198 @@
+package http
+
+import (
+	"balance/internal/domain/auth"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/ports"
+	"github.com/go-chi/chi"
+	"golang.org/x/time/rate"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	limiterIdleTimeout = 10 * time.Minute
+	poolSampleInterval = time.Second
+)
+
+type Limits struct {
+	// Rate is the default number of requests per second allowed for a client
+	// on a route, zero disables rate limiting.
+	Rate       float64
+	Burst      int
+	RouteRates map[string]float64
+	// TenantRates overrides Rate for the clients of a tenant, route rates
+	// still take precedence.
+	TenantRates map[string]float64
+
+	// MaxInFlight and MaxPoolWait bound concurrent requests and the average
+	// time spent waiting for a database connection, zero disables the check.
+	MaxInFlight int
+	MaxPoolWait time.Duration
+	Pool        ports.PoolStatsPort
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type rateLimiter struct {
+	limits    Limits
+	mu        sync.Mutex
+	limiters  map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+func newRateLimiter(limits Limits) *rateLimiter {
+	return &rateLimiter{
+		limits:   limits,
+		limiters: map[string]*clientLimiter{},
+	}
+}
+
+func (l *rateLimiter) reserve(tenant, client, route string, now time.Time) time.Duration {
+	limit := l.limits.Rate
+	if tenantRate, ok := l.limits.TenantRates[tenant]; ok {
+		limit = tenantRate
+	}
+	if routeRate, ok := l.limits.RouteRates[route]; ok {
+		limit = routeRate
+	}
+	if limit <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > limiterIdleTimeout {
+		for key, cl := range l.limiters {
+			if now.Sub(cl.lastSeen) > limiterIdleTimeout {
+				delete(l.limiters, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := tenant + " " + client + " " + route
+	cl, ok := l.limiters[key]
+	if !ok {
+		burst := l.limits.Burst
+		if burst <= 0 {
+			burst = int(math.Max(1, math.Ceil(limit)))
+		}
+		cl = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(limit), burst)}
+		l.limiters[key] = cl
+	}
+	cl.lastSeen = now
+
+	reservation := cl.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// concurrencyLimiter sheds load with an AIMD limit on in-flight requests: the
+// limit shrinks while the pool wait time is above the threshold and grows back
+// by one per sample otherwise.
+type concurrencyLimiter struct {
+	limits   Limits
+	inFlight int64
+
+	mu         sync.Mutex
+	limit      float64
+	lastSample time.Time
+	lastStats  models.PoolStats
+}
+
+func newConcurrencyLimiter(limits Limits) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		limits: limits,
+		limit:  float64(limits.MaxInFlight),
+	}
+}
+
+func (c *concurrencyLimiter) acquire(now time.Time) bool {
+	limit := c.currentLimit(now)
+	if atomic.AddInt64(&c.inFlight, 1) > limit && limit > 0 {
+		atomic.AddInt64(&c.inFlight, -1)
+		return false
+	}
+	return true
+}
+
+func (c *concurrencyLimiter) release() {
+	atomic.AddInt64(&c.inFlight, -1)
+}
+
+func (c *concurrencyLimiter) currentLimit(now time.Time) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.limits.Pool != nil && c.limits.MaxPoolWait > 0 && now.Sub(c.lastSample) >= poolSampleInterval {
+		stats := c.limits.Pool.PoolStats()
+		if acquires := stats.AcquireCount - c.lastStats.AcquireCount; !c.lastSample.IsZero() && acquires > 0 {
+			wait := (stats.AcquireDuration - c.lastStats.AcquireDuration) / time.Duration(acquires)
+			if wait > c.limits.MaxPoolWait {
+				if c.limit == 0 {
+					c.limit = float64(atomic.LoadInt64(&c.inFlight))
+				}
+				c.limit = math.Max(1, c.limit*0.9)
+			} else if c.limit > 0 {
+				c.limit++
+				if c.limits.MaxInFlight > 0 {
+					c.limit = math.Min(c.limit, float64(c.limits.MaxInFlight))
+				}
+			}
+		}
+		c.lastStats = stats
+		c.lastSample = now
+	}
+	return int64(c.limit)
+}
+
+func (s *Server) shedLoad(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.concurrency.acquire(time.Now()) {
+			w.Header().Set("Retry-After", "1")
+			s.writeProblem(w, r, e.OverloadedError)
+			return
+		}
+		defer s.concurrency.release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay := s.rates.reserve(tenant.FromContext(r.Context()), limitedClient(r),
+			chi.RouteContext(r.Context()).RoutePattern(), time.Now())
+		if delay > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			s.writeProblem(w, r, e.RateLimitedError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func limitedClient(r *http.Request) string {
+	if client, ok := auth.ClientFromContext(r.Context()); ok {
+		return client.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
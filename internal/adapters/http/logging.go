@@ -0,0 +1,98 @@
+package http
+
+import (
+	"balance/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/go-chi/chi"
+	"net/http"
+	"time"
+)
+
+const (
+	requestIdHeader    = "X-Request-ID"
+	maxRequestIdLength = 128
+)
+
+type accessEntry struct {
+	client string
+	userId int64
+	tenant string
+}
+
+type accessEntryKey struct{}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestId := r.Header.Get(requestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(requestIdHeader, requestId)
+
+		entry := &accessEntry{}
+		ctx := context.WithValue(r.Context(), accessEntryKey{}, entry)
+		ctx = utils.WithLogger(ctx, s.logger.With("request_id", requestId))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		fields := []interface{}{
+			"method", r.Method,
+			"route", chi.RouteContext(r.Context()).RoutePattern(),
+			"status", sw.status,
+			"latency", time.Since(start),
+		}
+		if entry.client != "" {
+			fields = append(fields, "client", entry.client)
+		}
+		if entry.userId != 0 {
+			fields = append(fields, "user_id", entry.userId)
+		}
+		if entry.tenant != "" {
+			fields = append(fields, "tenant", entry.tenant)
+		}
+		utils.Logger(ctx, s.logger).Infow("request", fields...)
+	})
+}
+
+func logClient(r *http.Request, client string) {
+	if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.client = client
+	}
+}
+
+func logUser(r *http.Request, userId int64) {
+	if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.userId = userId
+	}
+}
+
+func logTenant(r *http.Request, tenant string) {
+	if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.tenant = tenant
+	}
+}
+
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range requestId {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Balance API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4.15.0/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@4.15.0/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>`
+
+func LoadOpenAPI() (*openapi3.T, routers.Router, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openapi spec load failed: %w", err)
+	}
+	if err = spec.Validate(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("openapi spec is invalid: %w", err)
+	}
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openapi router init failed: %w", err)
+	}
+	return spec, router, nil
+}
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
+
+func (s *Server) docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerPage))
+}
+
+func (s *Server) validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := s.openAPIRouter.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		})
+		if err != nil {
+			s.writeBadRequest(w, r, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const problemTypePrefix = "urn:balance:problem:"
+
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Errors   []e.FieldError `json:"errors,omitempty"`
+}
+
+func problemStatus(err error) int {
+	switch {
+	case errors.Is(err, e.DatabaseError), errors.Is(err, e.InternalError):
+		return http.StatusInternalServerError
+	case errors.Is(err, e.UnknownUserIdError),
+		errors.Is(err, e.UnknownWebhookError),
+		errors.Is(err, e.UnknownDeadLetterError),
+		errors.Is(err, e.UnknownClientError),
+		errors.Is(err, e.UnknownAdjustmentError):
+		return http.StatusNotFound
+	case errors.Is(err, e.UnauthenticatedError):
+		return http.StatusUnauthorized
+	case errors.Is(err, e.ForbiddenError), errors.Is(err, e.SelfApprovalError):
+		return http.StatusForbidden
+	case errors.Is(err, e.NotEnoughUserBalanceError), errors.Is(err, e.TransferNotAllowedError):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, e.AccountFrozenError),
+		errors.Is(err, e.AccountExistsError),
+		errors.Is(err, e.AdjustmentNotPendingError),
+		errors.Is(err, e.AdjustmentExpiredError):
+		return http.StatusConflict
+	case errors.Is(err, e.RateLimitedError):
+		return http.StatusTooManyRequests
+	case errors.Is(err, e.OverloadedError):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	domainErr := e.InvalidRequestError
+	errors.As(err, &domainErr)
+
+	problem := Problem{
+		Type:     problemTypePrefix + domainErr.Code,
+		Title:    domainErr.Message,
+		Status:   problemStatus(err),
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+		Code:     domainErr.Code,
+	}
+	var validationErr *e.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.Errors = validationErr.Fields
+	}
+
+	response, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal problem fail: %v", marshalErr)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	w.Write(response)
+}
+
+func decodeJSON(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func (s *Server) writeBadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	s.writeProblem(w, r, fmt.Errorf("%w: %s", e.InvalidRequestError, detail))
+}
@@ -1,33 +1,73 @@
 package http
 
 import (
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
 	"balance/internal/ports"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/go-chi/chi"
 	"go.uber.org/zap"
 	"net"
 	"net/http"
+	"time"
 )
 
 type Server struct {
-	balance ports.BalancePort
-	server  *http.Server
-	logger  *zap.SugaredLogger
+	balance       ports.BalancePort
+	webhooks      ports.WebhookPort
+	auth          ports.AuthPort
+	tokens        ports.TokenVerifierPort
+	tenants       ports.TenantPort
+	rates         *rateLimiter
+	concurrency   *concurrencyLimiter
+	instrument    func(http.Handler) http.Handler
+	metrics       http.Handler
+	health        ports.HealthPort
+	adjustments   ports.AdjustmentPort
+	timeout       time.Duration
+	server        *http.Server
+	openAPIRouter routers.Router
+	logger        *zap.SugaredLogger
 }
 
-func New(balance ports.BalancePort, logger *zap.SugaredLogger) *Server {
-	return &Server{balance: balance, server: &http.Server{}, logger: logger}
+// New creates the HTTP server. A nil auth disables API key authentication,
+// a nil tokens disables end-user JWT authorization, a nil webhooks disables
+// the webhook endpoints.
+func New(balance ports.BalancePort, webhooks ports.WebhookPort, auth ports.AuthPort,
+	tokens ports.TokenVerifierPort, limits Limits, logger *zap.SugaredLogger) (*Server, error) {
+	_, openAPIRouter, err := LoadOpenAPI()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		balance:       balance,
+		webhooks:      webhooks,
+		auth:          auth,
+		tokens:        tokens,
+		tenants:       tenant.NewRegistry(models.TenantSettings{}, nil),
+		rates:         newRateLimiter(limits),
+		concurrency:   newConcurrencyLimiter(limits),
+		server:        &http.Server{},
+		openAPIRouter: openAPIRouter,
+		logger:        logger,
+	}, nil
 }
 
-func (s *Server) Start(port string) error {
+// Start serves plaintext HTTP when tlsConfig is nil and HTTPS otherwise.
+func (s *Server) Start(port string, tlsConfig *tls.Config) error {
 	listen, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		return fmt.Errorf("failed to listen on port %s: %v", err, port)
 	}
+	if tlsConfig != nil {
+		listen = tls.NewListener(listen, tlsConfig)
+	}
 
-	s.server.Handler = s.routes()
+	s.server.Handler = s.Handler()
 
 	if err := s.server.Serve(listen); !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to serve http server over port %s: %v", err, port)
@@ -35,10 +75,71 @@ func (s *Server) Start(port string) error {
 	return nil
 }
 
-func (s *Server) routes() http.Handler {
+// WithMetrics installs the request instrumentation middleware on the router
+// and exposes the metrics handler on /metrics.
+func (s *Server) WithMetrics(instrument func(http.Handler) http.Handler, metrics http.Handler) {
+	s.instrument = instrument
+	s.metrics = metrics
+}
+
+// WithAdjustments exposes maker-checker balance adjustments on the admin API.
+func (s *Server) WithAdjustments(adjustments ports.AdjustmentPort) {
+	s.adjustments = adjustments
+}
+
+// WithTenants sets the tenants requests may address, by default only the
+// default tenant exists.
+func (s *Server) WithTenants(tenants ports.TenantPort) {
+	s.tenants = tenants
+}
+
+// WithTimeouts sets the read and write timeouts of the underlying server and
+// the deadline of every API request context. Zero disables a timeout.
+func (s *Server) WithTimeouts(read, write, request time.Duration) {
+	s.server.ReadTimeout = read
+	s.server.WriteTimeout = write
+	s.timeout = request
+}
+
+func (s *Server) withTimeout(next http.Handler) http.Handler {
+	if s.timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) Handler() http.Handler {
 	r := chi.NewMux()
+	r.Use(s.trace)
+	r.Use(s.logRequests)
+	if s.instrument != nil {
+		r.Use(s.instrument)
+	}
 	r.Get("/health", s.healthHandler)
-	r.Mount("/balance/v1/", s.balanceHandlers())
+	r.Get("/livez", s.livenessHandler)
+	r.Get("/readyz", s.readinessHandler)
+	r.Get("/openapi.json", s.openAPIHandler)
+	r.Get("/docs", s.docsHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(s.shedLoad)
+		r.Use(s.withTimeout)
+		r.Use(s.authenticate)
+		r.Group(func(r chi.Router) {
+			r.Use(s.validateRequest)
+			if s.webhooks != nil {
+				r.With(s.requireScope(models.ScopeWebhooks)).Mount("/balance/v1/webhooks", s.webhookHandlers())
+			}
+			r.Mount("/balance/v1/", s.balanceHandlers())
+		})
+		r.With(s.requireScope(models.ScopeAdmin)).Mount("/admin/v1/", s.adminHandlers())
+		if s.metrics != nil {
+			r.With(s.requireScope(models.ScopeAdmin)).Handle("/metrics", s.metrics)
+		}
+	})
 	return r
 }
 
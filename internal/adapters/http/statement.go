@@ -0,0 +1,110 @@
+package http
+
+import (
+	"balance/internal/adapters/statement"
+	"balance/internal/domain/tenant"
+	"balance/internal/ports"
+	"balance/internal/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type trackingWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+func parseStatementTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+// parseStatementEnd parses the exclusive end of a period, a plain date ends
+// the period after that day so its transactions are included.
+func parseStatementEnd(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, 1), nil
+}
+
+func (s *Server) getStatement(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	idRaw := query.Get("user_id")
+	if idRaw == "" {
+		s.writeBadRequest(w, r, "missing required user_id parameter")
+		return
+	}
+	id, err := strconv.ParseInt(idRaw, 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect user_id parameter")
+		return
+	}
+	logUser(r, id)
+
+	from := time.Time{}
+	if raw := query.Get("from"); raw != "" {
+		from, err = parseStatementTime(raw)
+		if err != nil {
+			s.writeBadRequest(w, r, "incorrect from parameter")
+			return
+		}
+	}
+	to := time.Now()
+	if raw := query.Get("to"); raw != "" {
+		to, err = parseStatementEnd(raw)
+		if err != nil {
+			s.writeBadRequest(w, r, "incorrect to parameter")
+			return
+		}
+	}
+
+	out := &trackingWriter{w: w}
+	var writer ports.StatementWriter
+	var contentType, extension string
+
+	switch query.Get("format") {
+	case "", "csv":
+		writer = statement.NewCSV(out)
+		contentType, extension = "text/csv", "csv"
+	case "xlsx":
+		writer = statement.NewXLSX(out)
+		contentType, extension = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+	case "camt053":
+		writer = statement.NewCamt053(out)
+		contentType, extension = "application/xml", "xml"
+	default:
+		s.writeBadRequest(w, r, "incorrect format parameter")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"statement_%d.%s\"", id, extension))
+
+	err = s.balance.GetStatement(r.Context(), tenant.FromContext(r.Context()), id, from, to, writer)
+
+	if err != nil {
+		if out.written {
+			utils.Logger(r.Context(), s.logger).Errorf("statement streaming interrupted: %v", err)
+			return
+		}
+		w.Header().Del("Content-Disposition")
+		s.writeProblem(w, r, err)
+		return
+	}
+}
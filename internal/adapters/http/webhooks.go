@@ -0,0 +1,151 @@
+package http
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"balance/internal/utils"
+	"encoding/json"
+	"github.com/go-chi/chi"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) webhookHandlers() http.Handler {
+	h := chi.NewMux()
+	r := h.With(s.rateLimit)
+	r.Post("/", s.subscribeWebhook)
+	r.Get("/", s.listWebhooks)
+	r.Delete("/{id}", s.unsubscribeWebhook)
+	return h
+}
+
+func (s *Server) adminHandlers() http.Handler {
+	h := chi.NewMux()
+	r := h.With(s.rateLimit)
+	r.Post("/clients", s.createClient)
+	r.Get("/clients", s.listClients)
+	if s.webhooks != nil {
+		r.Get("/webhooks/dead-letters", s.listDeadLetters)
+		r.Post("/webhooks/dead-letters/{id}/replay", s.replayDeadLetter)
+	}
+	r.Post("/accounts", s.createAccount)
+	r.Get("/accounts/{user_id}", s.getAccount)
+	r.Post("/accounts/{user_id}/freeze", s.freezeAccount)
+	r.Post("/accounts/{user_id}/unfreeze", s.unfreezeAccount)
+	r.Get("/reconciliation", s.reconcile)
+	if s.adjustments != nil {
+		r.Post("/adjustments", s.proposeAdjustment)
+		r.Get("/adjustments", s.listAdjustments)
+		r.Get("/adjustments/{id}", s.getAdjustment)
+		r.Post("/adjustments/{id}/approve", s.approveAdjustment)
+		r.Post("/adjustments/{id}/reject", s.rejectAdjustment)
+	}
+	return h
+}
+
+func (s *Server) subscribeWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+	subscriptionParams := &models.WebhookSubscription{}
+	err = decodeJSON(body, subscriptionParams)
+	if err != nil {
+		s.writeBadRequest(w, r, err.Error())
+		return
+	}
+
+	subscription, err := s.webhooks.Subscribe(r.Context(), tenant.FromContext(r.Context()), *subscriptionParams)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	response, err := json.Marshal(subscription)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(response)
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := s.webhooks.ListSubscriptions(r.Context(), tenant.FromContext(r.Context()))
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	response, err := json.Marshal(subscriptions)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (s *Server) unsubscribeWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect id parameter")
+		return
+	}
+
+	err = s.webhooks.Unsubscribe(r.Context(), tenant.FromContext(r.Context()), id)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{\"status\": \"success\"}"))
+}
+
+func (s *Server) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deadLetters, err := s.webhooks.ListDeadLetters(r.Context(), tenant.FromContext(r.Context()))
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+
+	response, err := json.Marshal(deadLetters)
+	if err != nil {
+		utils.Logger(r.Context(), s.logger).Errorf("marshal response fail: %v", err)
+		s.writeProblem(w, r, e.InternalError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (s *Server) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.writeBadRequest(w, r, "incorrect id parameter")
+		return
+	}
+
+	err = s.webhooks.Replay(r.Context(), tenant.FromContext(r.Context()), id)
+
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("{\"status\": \"queued\"}"))
+}
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) AddAdjustment(ctx context.Context, tenant string,
+	adjustment models.Adjustment) (models.Adjustment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Adjustment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	adjustment.Id = int64(len(s.adjustments)) + 1
+	adjustment.Events = nil
+	s.adjustments = append(s.adjustments, adjustment)
+	s.adjustmentTenants = append(s.adjustmentTenants, tenant)
+	s.recordAdjustmentEvent(models.AdjustmentEvent{
+		AdjustmentId: adjustment.Id,
+		Action:       models.AdjustmentProposed,
+		Client:       adjustment.ProposedBy,
+		CreatedAt:    adjustment.CreatedAt,
+	})
+	return adjustment, nil
+}
+
+func (s *Storage) GetAdjustment(ctx context.Context, tenant string, id int64) (models.Adjustment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Adjustment{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.adjustment(tenant, id)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	adjustment := *stored
+	adjustment.Events = []models.AdjustmentEvent{}
+	for _, event := range s.adjustmentEvents {
+		if event.AdjustmentId == id {
+			adjustment.Events = append(adjustment.Events, event)
+		}
+	}
+	return adjustment, nil
+}
+
+func (s *Storage) ListAdjustments(ctx context.Context, tenant string,
+	filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	adjustments := []models.Adjustment{}
+	for i, adjustment := range s.adjustments {
+		if s.adjustmentTenants[i] != tenant {
+			continue
+		}
+		if filter.UserId != 0 && adjustment.UserId != filter.UserId {
+			continue
+		}
+		if filter.Status != "" && adjustment.Status != filter.Status {
+			continue
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	return adjustments, nil
+}
+
+func (s *Storage) TransitionAdjustment(ctx context.Context, tenant string, id int64, from string,
+	event models.AdjustmentEvent) (models.Adjustment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Adjustment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	adjustment, err := s.adjustment(tenant, id)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	if adjustment.Status != from {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d is %s: %w", id, adjustment.Status,
+			errors.AdjustmentNotPendingError)
+	}
+	s.transition(adjustment, event)
+	return *adjustment, nil
+}
+
+// PostAdjustment calls post under the write lock, the balance changes made
+// with the transaction it gets do not lock again.
+func (s *Storage) PostAdjustment(ctx context.Context, tenant string, id int64, event models.AdjustmentEvent,
+	post func(tx ports.Tx, adjustment models.Adjustment) error) (models.Adjustment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Adjustment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	adjustment, err := s.adjustment(tenant, id)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	if adjustment.Status != models.AdjustmentPending {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d is %s: %w", id, adjustment.Status,
+			errors.AdjustmentNotPendingError)
+	}
+
+	if err = post(&lockedTx{storage: s}, *adjustment); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	event.Action = models.AdjustmentApproved
+	s.transition(adjustment, event)
+	event.Action = models.AdjustmentPosted
+	s.transition(adjustment, event)
+	return *adjustment, nil
+}
+
+func (s *Storage) ExpireAdjustments(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired int64
+	for i := range s.adjustments {
+		adjustment := &s.adjustments[i]
+		if adjustment.Status != models.AdjustmentPending || adjustment.ExpiresAt.After(now) {
+			continue
+		}
+		s.transition(adjustment, models.AdjustmentEvent{Action: models.AdjustmentExpired, CreatedAt: now})
+		expired++
+	}
+	return expired, nil
+}
+
+// adjustment returns the adjustment if it exists in the tenant, adjustments
+// of other tenants are reported as unknown.
+func (s *Storage) adjustment(tenant string, id int64) (*models.Adjustment, error) {
+	if id <= 0 || id > int64(len(s.adjustments)) || s.adjustmentTenants[id-1] != tenant {
+		return nil, fmt.Errorf("adjustment %d: %w", id, errors.UnknownAdjustmentError)
+	}
+	return &s.adjustments[id-1], nil
+}
+
+func (s *Storage) transition(adjustment *models.Adjustment, event models.AdjustmentEvent) {
+	adjustment.Status = event.Action
+	if event.Action == models.AdjustmentApproved || event.Action == models.AdjustmentRejected ||
+		event.Action == models.AdjustmentFailed {
+		decidedAt := event.CreatedAt
+		adjustment.DecidedBy = event.Client
+		adjustment.DecidedAt = &decidedAt
+	}
+	event.AdjustmentId = adjustment.Id
+	s.recordAdjustmentEvent(event)
+}
+
+func (s *Storage) recordAdjustmentEvent(event models.AdjustmentEvent) {
+	event.Id = int64(len(s.adjustmentEvents)) + 1
+	s.adjustmentEvents = append(s.adjustmentEvents, event)
+}
@@ -0,0 +1,403 @@
+package memory
+
+import (
+	"balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage keeps balances and history in process memory with the same
+// semantics as the Postgres storage. It is meant for local development and
+// tests, everything is lost on restart.
+type Storage struct {
+	mu                sync.RWMutex
+	ledgers           map[string]*ledger
+	lastHistoryId     int64
+	adjustments       []models.Adjustment
+	adjustmentTenants []string
+	adjustmentEvents  []models.AdjustmentEvent
+}
+
+// ledger holds the accounts of a single tenant.
+type ledger struct {
+	accounts map[int64]models.Account
+	balances map[int64]decimal.Decimal
+	frozen   map[int64]bool
+	history  []models.Transaction
+}
+
+func New() *Storage {
+	return &Storage{
+		ledgers: map[string]*ledger{},
+	}
+}
+
+// ledger returns the ledger of the tenant, creating it on first write. The
+// caller must hold the write lock.
+func (s *Storage) ledger(tenant string) *ledger {
+	l, ok := s.ledgers[tenant]
+	if !ok {
+		l = &ledger{
+			accounts: map[int64]models.Account{},
+			balances: map[int64]decimal.Decimal{},
+			frozen:   map[int64]bool{},
+		}
+		s.ledgers[tenant] = l
+	}
+	return l
+}
+
+// readLedger returns the ledger of the tenant or an empty one, it does not
+// modify the storage and is safe under the read lock.
+func (s *Storage) readLedger(tenant string) *ledger {
+	if l, ok := s.ledgers[tenant]; ok {
+		return l
+	}
+	return &ledger{}
+}
+
+func (s *Storage) record(l *ledger, transaction models.Transaction) {
+	s.lastHistoryId++
+	transaction.Id = s.lastHistoryId
+	l.history = append(l.history, transaction)
+}
+
+func (s *Storage) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Account{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.ledger(tenant)
+	if _, ok := l.balances[account.Id]; ok {
+		return models.Account{}, fmt.Errorf("user_id %d: %w", account.Id, errors.AccountExistsError)
+	}
+	l.accounts[account.Id] = account
+	l.balances[account.Id] = decimal.Zero
+	return account, nil
+}
+
+func (s *Storage) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Account{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, ok := s.readLedger(tenant).accounts[id]
+	if !ok {
+		return models.Account{}, fmt.Errorf("user_id %d: %w", id, errors.UnknownUserIdError)
+	}
+	return account, nil
+}
+
+// credit adds value to the account, creating a user account on first credit
+func (l *ledger) credit(userId int64, value decimal.Decimal) {
+	if _, ok := l.balances[userId]; !ok {
+		l.accounts[userId] = models.Account{Id: userId, Type: models.AccountUser, CreatedAt: time.Now()}
+	}
+	l.balances[userId] = l.balances[userId].Add(value)
+}
+
+func (s *Storage) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addIncome(s.ledger(tenant), income)
+}
+
+// lockedTx is handed out while the storage holds the write lock. A failed
+// change leaves the ledger untouched, so there is nothing to roll back.
+type lockedTx struct {
+	storage *Storage
+}
+
+// ownTx checks that tx was opened by this storage, which holds the lock.
+func (s *Storage) ownTx(tx ports.Tx) error {
+	if locked, ok := tx.(*lockedTx); !ok || locked.storage != s {
+		return fmt.Errorf("unexpected transaction type %T", tx)
+	}
+	return nil
+}
+
+func (s *Storage) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string, income models.BalanceWithDesc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.ownTx(tx); err != nil {
+		return err
+	}
+	return s.addIncome(s.ledger(tenant), income)
+}
+
+// addIncome credits the account and records the income, the caller must hold
+// the write lock.
+func (s *Storage) addIncome(l *ledger, income models.BalanceWithDesc) error {
+	if l.frozen[income.UserId] {
+		return fmt.Errorf("user_id %d: %w", income.UserId, errors.AccountFrozenError)
+	}
+	l.credit(income.UserId, income.Value)
+	s.record(l, models.Transaction{
+		UserIdTo:    income.UserId,
+		Value:       income.Value,
+		Time:        income.Time,
+		Description: income.Description,
+		Client:      income.Client,
+	})
+	return nil
+}
+
+func (s *Storage) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addExpense(s.ledger(tenant), expense)
+}
+
+func (s *Storage) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.ownTx(tx); err != nil {
+		return err
+	}
+	return s.addExpense(s.ledger(tenant), expense)
+}
+
+// addExpense debits the account and records the expense, the caller must hold
+// the write lock.
+func (s *Storage) addExpense(l *ledger, expense models.BalanceWithDesc) error {
+	if err := l.withdraw(expense.UserId, expense.Value); err != nil {
+		return err
+	}
+	s.record(l, models.Transaction{
+		UserIdFrom:  expense.UserId,
+		Value:       expense.Value,
+		Time:        expense.Time,
+		Description: expense.Description,
+		Client:      expense.Client,
+	})
+	return nil
+}
+
+func (s *Storage) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.ledger(tenant)
+	if from, ok := l.accounts[transaction.UserIdFrom]; ok && transaction.Rules != nil {
+		// a missing receiving account is created as a user account
+		to, ok := l.accounts[transaction.UserIdTo]
+		if !ok {
+			to.Type = models.AccountUser
+		}
+		if !transaction.Rules.Allows(from.Type, to.Type) {
+			return fmt.Errorf("%s to %s: %w", from.Type, to.Type, errors.TransferNotAllowedError)
+		}
+	}
+	if _, ok := l.balances[transaction.UserIdFrom]; ok && l.frozen[transaction.UserIdTo] {
+		return fmt.Errorf("user_id %d: %w", transaction.UserIdTo, errors.AccountFrozenError)
+	}
+	if err := l.withdraw(transaction.UserIdFrom, transaction.Value); err != nil {
+		return err
+	}
+	l.credit(transaction.UserIdTo, transaction.Value)
+	transaction.Rules = nil
+	s.record(l, transaction)
+	return nil
+}
+
+func (l *ledger) withdraw(userId int64, value decimal.Decimal) error {
+	balance, ok := l.balances[userId]
+	if !ok {
+		return fmt.Errorf("user_id %d: %w", userId, errors.UnknownUserIdError)
+	}
+	if l.frozen[userId] {
+		return fmt.Errorf("user_id %d: %w", userId, errors.AccountFrozenError)
+	}
+	balance = balance.Sub(value)
+	if balance.IsNegative() {
+		return fmt.Errorf("user_id %d: %w", userId, errors.NotEnoughUserBalanceError)
+	}
+	l.balances[userId] = balance
+	return nil
+}
+
+func (s *Storage) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Balance{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.readLedger(tenant)
+	balance, ok := l.balances[userId]
+	if !ok {
+		return models.Balance{}, fmt.Errorf("user_id %d: %w", userId, errors.UnknownUserIdError)
+	}
+	return models.Balance{UserId: userId, Value: balance, Frozen: l.frozen[userId]}, nil
+}
+
+func (s *Storage) GetBalanceAt(ctx context.Context, tenant string, userId int64,
+	at time.Time) (decimal.Decimal, error) {
+	if err := ctx.Err(); err != nil {
+		return decimal.Decimal{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.readLedger(tenant).balanceAt(userId, at), nil
+}
+
+func (l *ledger) balanceAt(userId int64, at time.Time) decimal.Decimal {
+	balance := decimal.Zero
+	for _, transaction := range l.history {
+		if !transaction.Time.Before(at) {
+			continue
+		}
+		if transaction.UserIdTo == userId {
+			balance = balance.Add(transaction.Value)
+		}
+		if transaction.UserIdFrom == userId {
+			balance = balance.Sub(transaction.Value)
+		}
+	}
+	return balance
+}
+
+// GetHistory calls fn outside of the lock on a snapshot of the matching
+// transactions, so a slow consumer does not block writers.
+func (s *Storage) GetHistory(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	fn func(models.Transaction) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	transactions := s.readLedger(tenant).between(userId, from, to)
+	s.mu.RUnlock()
+
+	return each(transactions, fn)
+}
+
+// between returns the transactions of the user in [from, to) ordered by time.
+func (l *ledger) between(userId int64, from, to time.Time) []models.Transaction {
+	var transactions []models.Transaction
+	for _, transaction := range l.history {
+		if transaction.UserIdFrom != userId && transaction.UserIdTo != userId {
+			continue
+		}
+		if transaction.Time.Before(from) || !transaction.Time.Before(to) {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Time.Before(transactions[j].Time)
+	})
+	return transactions
+}
+
+func each(transactions []models.Transaction, fn func(models.Transaction) error) error {
+	for _, transaction := range transactions {
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatement takes the balances and the transactions under one lock and
+// calls header and fn outside of it.
+func (s *Storage) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	header func(opening, closing decimal.Decimal) error, fn func(models.Transaction) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	l := s.readLedger(tenant)
+	opening := l.balanceAt(userId, from)
+	closing := l.balanceAt(userId, to)
+	transactions := l.between(userId, from, to)
+	s.mu.RUnlock()
+
+	if err := header(opening, closing); err != nil {
+		return err
+	}
+	return each(transactions, fn)
+}
+
+func (s *Storage) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.ledger(tenant)
+	if _, ok := l.balances[userId]; !ok {
+		return fmt.Errorf("user_id %d: %w", userId, errors.UnknownUserIdError)
+	}
+	l.frozen[userId] = frozen
+	return nil
+}
+
+func (s *Storage) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Reconciliation{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.readLedger(tenant)
+	totals := map[int64]decimal.Decimal{}
+	for _, transaction := range l.history {
+		totals[transaction.UserIdTo] = totals[transaction.UserIdTo].Add(transaction.Value)
+		totals[transaction.UserIdFrom] = totals[transaction.UserIdFrom].Sub(transaction.Value)
+	}
+	delete(totals, 0)
+
+	userIds := map[int64]bool{}
+	for userId := range l.balances {
+		userIds[userId] = true
+	}
+	for userId := range totals {
+		userIds[userId] = true
+	}
+
+	reconciliation := models.Reconciliation{
+		Accounts:   int64(len(l.balances)),
+		Mismatches: []models.ReconciliationMismatch{},
+	}
+	for userId := range userIds {
+		if !l.balances[userId].Equal(totals[userId]) {
+			reconciliation.Mismatches = append(reconciliation.Mismatches, models.ReconciliationMismatch{
+				UserId:         userId,
+				Balance:        l.balances[userId],
+				HistoryBalance: totals[userId],
+			})
+		}
+	}
+	sort.Slice(reconciliation.Mismatches, func(i, j int) bool {
+		return reconciliation.Mismatches[i].UserId < reconciliation.Mismatches[j].UserId
+	})
+	return reconciliation, nil
+}
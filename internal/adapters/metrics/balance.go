@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type balance struct {
+	next    ports.BalancePort
+	metrics *Metrics
+}
+
+func (m *Metrics) Balance(next ports.BalancePort) ports.BalancePort {
+	return &balance{next: next, metrics: m}
+}
+
+func (b *balance) observe(operation string, amount decimal.Decimal, err error) {
+	b.metrics.operations.WithLabelValues(operation, result(err)).Inc()
+	if err != nil {
+		b.metrics.domainErrors.WithLabelValues(operation, errorCode(err)).Inc()
+		return
+	}
+	if !amount.IsZero() {
+		b.metrics.operationAmount.WithLabelValues(operation).Add(amount.InexactFloat64())
+	}
+}
+
+func (b *balance) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	account, err := b.next.CreateAccount(ctx, tenant, account)
+	b.observe("create_account", decimal.Zero, err)
+	return account, err
+}
+
+func (b *balance) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	account, err := b.next.GetAccount(ctx, tenant, id)
+	b.observe("account", decimal.Zero, err)
+	return account, err
+}
+
+func (b *balance) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	err := b.next.AddIncome(ctx, tenant, income)
+	b.observe("income", income.Value, err)
+	return err
+}
+
+func (b *balance) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	err := b.next.AddExpense(ctx, tenant, expense)
+	b.observe("expense", expense.Value, err)
+	return err
+}
+
+func (b *balance) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	income models.BalanceWithDesc) error {
+	err := b.next.AddIncomeTx(ctx, tx, tenant, income)
+	b.observe("income", income.Value, err)
+	return err
+}
+
+func (b *balance) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	err := b.next.AddExpenseTx(ctx, tx, tenant, expense)
+	b.observe("expense", expense.Value, err)
+	return err
+}
+
+func (b *balance) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	err := b.next.DoTransfer(ctx, tenant, transaction)
+	b.observe("transfer", transaction.Value, err)
+	return err
+}
+
+func (b *balance) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	balance, err := b.next.GetBalance(ctx, tenant, userId)
+	b.observe("balance", decimal.Zero, err)
+	return balance, err
+}
+
+func (b *balance) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	w ports.StatementWriter) error {
+	err := b.next.GetStatement(ctx, tenant, userId, from, to, w)
+	b.observe("statement", decimal.Zero, err)
+	return err
+}
+
+func (b *balance) GetHistory(ctx context.Context, tenant string, userId int64,
+	from, to time.Time) ([]models.Transaction, error) {
+	history, err := b.next.GetHistory(ctx, tenant, userId, from, to)
+	b.observe("history", decimal.Zero, err)
+	return history, err
+}
+
+func (b *balance) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	err := b.next.SetFrozen(ctx, tenant, userId, frozen)
+	b.observe("freeze", decimal.Zero, err)
+	return err
+}
+
+func (b *balance) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	reconciliation, err := b.next.Reconcile(ctx, tenant)
+	b.observe("reconcile", decimal.Zero, err)
+	return reconciliation, err
+}
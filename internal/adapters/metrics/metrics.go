@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	e "balance/internal/domain/errors"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "balance"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	operations      *prometheus.CounterVec
+	operationAmount *prometheus.CounterVec
+	domainErrors    *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+	deliveryLag     *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Balance operations by type and result.",
+		}, []string{"type", "result"}),
+		operationAmount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operation_amount_total",
+			Help:      "Sum of successful balance operation amounts by type.",
+		}, []string{"type"}),
+		domainErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "domain_errors_total",
+			Help:      "Domain errors returned by balance operations by code.",
+		}, []string{"operation", "code"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_duration_seconds",
+			Help:      "Balance storage call latency by method and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "result"}),
+		deliveryLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "event_delivery_lag_seconds",
+			Help:      "Time between an event being written to the outbox and its delivery by publisher.",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 2, 5, 10, 30, 60, 300},
+		}, []string{"publisher", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.operations,
+		m.operationAmount,
+		m.domainErrors,
+		m.storageDuration,
+		m.deliveryLag,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Register(collectors ...prometheus.Collector) {
+	m.registry.MustRegister(collectors...)
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+func errorCode(err error) string {
+	var domainErr *e.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return "unknown"
+}
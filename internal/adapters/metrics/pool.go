@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"balance/internal/ports"
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns",
+		"Connections currently acquired from the pool.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(namespace+"_pgxpool_idle_conns",
+		"Idle connections in the pool.", nil, nil)
+	poolTotalDesc = prometheus.NewDesc(namespace+"_pgxpool_total_conns",
+		"Total connections in the pool.", nil, nil)
+	poolMaxDesc = prometheus.NewDesc(namespace+"_pgxpool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquireCountDesc = prometheus.NewDesc(namespace+"_pgxpool_acquires_total",
+		"Successful connection acquires from the pool.", nil, nil)
+	poolAcquireDurationDesc = prometheus.NewDesc(namespace+"_pgxpool_acquire_duration_seconds_total",
+		"Total time spent acquiring connections from the pool.", nil, nil)
+
+	outboxPendingDesc = prometheus.NewDesc(namespace+"_outbox_pending_events",
+		"Outbox events not yet delivered.", nil, nil)
+	outboxLagDesc = prometheus.NewDesc(namespace+"_outbox_lag_seconds",
+		"Age of the oldest undelivered outbox event.", nil, nil)
+)
+
+type poolCollector struct {
+	pool ports.PoolStatsPort
+}
+
+func NewPoolCollector(pool ports.PoolStatsPort) prometheus.Collector {
+	return &poolCollector{pool: pool}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredDesc
+	ch <- poolIdleDesc
+	ch <- poolTotalDesc
+	ch <- poolMaxDesc
+	ch <- poolAcquireCountDesc
+	ch <- poolAcquireDurationDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.PoolStats()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredDesc, prometheus.GaugeValue, float64(stats.AcquiredConns))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stats.MaxConns))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCountDesc, prometheus.CounterValue, float64(stats.AcquireCount))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDurationDesc, prometheus.CounterValue,
+		stats.AcquireDuration.Seconds())
+}
+
+type outboxCollector struct {
+	storage ports.OutboxStoragePort
+	timeout time.Duration
+}
+
+func NewOutboxCollector(storage ports.OutboxStoragePort, timeout time.Duration) prometheus.Collector {
+	return &outboxCollector{storage: storage, timeout: timeout}
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- outboxPendingDesc
+	ch <- outboxLagDesc
+}
+
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.storage.GetOutboxStats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(outboxPendingDesc, err)
+		return
+	}
+	lag := 0.0
+	if !stats.OldestPending.IsZero() {
+		lag = time.Since(stats.OldestPending).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(outboxPendingDesc, prometheus.GaugeValue, float64(stats.Pending))
+	ch <- prometheus.MustNewConstMetric(outboxLagDesc, prometheus.GaugeValue, lag)
+}
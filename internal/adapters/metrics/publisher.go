@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"time"
+)
+
+type publisher struct {
+	name    string
+	next    ports.EventPublisher
+	metrics *Metrics
+}
+
+func (m *Metrics) Publisher(name string, next ports.EventPublisher) ports.EventPublisher {
+	return &publisher{name: name, next: next, metrics: m}
+}
+
+func (p *publisher) Publish(ctx context.Context, event models.Event) error {
+	err := p.next.Publish(ctx, event)
+	p.metrics.deliveryLag.WithLabelValues(p.name, result(err)).Observe(time.Since(event.CreatedAt).Seconds())
+	return err
+}
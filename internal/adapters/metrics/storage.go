@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type storage struct {
+	next    ports.BalanceStoragePort
+	metrics *Metrics
+}
+
+func (m *Metrics) Storage(next ports.BalanceStoragePort) ports.BalanceStoragePort {
+	return &storage{next: next, metrics: m}
+}
+
+func (s *storage) observe(method string, start time.Time, err error) {
+	s.metrics.storageDuration.WithLabelValues(method, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func (s *storage) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	start := time.Now()
+	account, err := s.next.CreateAccount(ctx, tenant, account)
+	s.observe("CreateAccount", start, err)
+	return account, err
+}
+
+func (s *storage) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	start := time.Now()
+	account, err := s.next.GetAccount(ctx, tenant, id)
+	s.observe("GetAccount", start, err)
+	return account, err
+}
+
+func (s *storage) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	start := time.Now()
+	err := s.next.AddIncome(ctx, tenant, income)
+	s.observe("AddIncome", start, err)
+	return err
+}
+
+func (s *storage) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	start := time.Now()
+	err := s.next.AddExpense(ctx, tenant, expense)
+	s.observe("AddExpense", start, err)
+	return err
+}
+
+func (s *storage) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	income models.BalanceWithDesc) error {
+	start := time.Now()
+	err := s.next.AddIncomeTx(ctx, tx, tenant, income)
+	s.observe("AddIncomeTx", start, err)
+	return err
+}
+
+func (s *storage) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	start := time.Now()
+	err := s.next.AddExpenseTx(ctx, tx, tenant, expense)
+	s.observe("AddExpenseTx", start, err)
+	return err
+}
+
+func (s *storage) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	start := time.Now()
+	err := s.next.DoTransfer(ctx, tenant, transaction)
+	s.observe("DoTransfer", start, err)
+	return err
+}
+
+func (s *storage) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	start := time.Now()
+	balance, err := s.next.GetBalance(ctx, tenant, userId)
+	s.observe("GetBalance", start, err)
+	return balance, err
+}
+
+func (s *storage) GetBalanceAt(ctx context.Context, tenant string, userId int64,
+	at time.Time) (decimal.Decimal, error) {
+	start := time.Now()
+	balance, err := s.next.GetBalanceAt(ctx, tenant, userId, at)
+	s.observe("GetBalanceAt", start, err)
+	return balance, err
+}
+
+func (s *storage) GetHistory(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	fn func(models.Transaction) error) error {
+	start := time.Now()
+	err := s.next.GetHistory(ctx, tenant, userId, from, to, fn)
+	s.observe("GetHistory", start, err)
+	return err
+}
+
+func (s *storage) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	header func(opening, closing decimal.Decimal) error, fn func(models.Transaction) error) error {
+	start := time.Now()
+	err := s.next.GetStatement(ctx, tenant, userId, from, to, header, fn)
+	s.observe("GetStatement", start, err)
+	return err
+}
+
+func (s *storage) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	start := time.Now()
+	err := s.next.SetFrozen(ctx, tenant, userId, frozen)
+	s.observe("SetFrozen", start, err)
+	return err
+}
+
+func (s *storage) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	start := time.Now()
+	reconciliation, err := s.next.Reconcile(ctx, tenant)
+	s.observe("Reconcile", start, err)
+	return reconciliation, err
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const adjustmentColumns = `id, user_id, value::text, reason, COALESCE(attachment_ref, ''), status,
+	COALESCE(proposed_by, ''), COALESCE(decided_by, ''), created_at, expires_at, decided_at`
+
+func (db *Database) AddAdjustment(ctx context.Context, tenant string,
+	adjustment models.Adjustment) (models.Adjustment, error) {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO balance.adjustments
+				(tenant, user_id, value, reason, attachment_ref, status, proposed_by, created_at, expires_at)
+			VALUES
+				($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)
+			RETURNING id`,
+		tenant, adjustment.UserId, adjustment.Value, adjustment.Reason, adjustment.AttachmentRef, adjustment.Status,
+		adjustment.ProposedBy, adjustment.CreatedAt, adjustment.ExpiresAt).Scan(&adjustment.Id)
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("add adjustment query row failed: %w", err)
+	}
+
+	err = insertAdjustmentEvent(ctx, tx, tenant, models.AdjustmentEvent{
+		AdjustmentId: adjustment.Id,
+		Action:       models.AdjustmentProposed,
+		Client:       adjustment.ProposedBy,
+		CreatedAt:    adjustment.CreatedAt,
+	})
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Adjustment{}, fmt.Errorf("tx commit failed: %w", err)
+	}
+	adjustment.Events = nil
+	return adjustment, nil
+}
+
+func (db *Database) GetAdjustment(ctx context.Context, tenant string, id int64) (models.Adjustment, error) {
+	adjustment, err := scanAdjustment(db.DB.QueryRow(ctx,
+		"SELECT "+adjustmentColumns+" FROM balance.adjustments WHERE tenant = $1 AND id = $2", tenant, id))
+	if err == pgx.ErrNoRows {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d: %w", id, errors.UnknownAdjustmentError)
+	}
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("get adjustment query row failed: %w", err)
+	}
+
+	rows, err := db.DB.Query(ctx,
+		`SELECT id, adjustment_id, action, COALESCE(client, ''), COALESCE(comment, ''), created_at
+			FROM balance.adjustment_events
+			WHERE tenant = $1 AND adjustment_id = $2
+			ORDER BY id`,
+		tenant, id)
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("list adjustment events query failed: %w", err)
+	}
+	defer rows.Close()
+
+	adjustment.Events = []models.AdjustmentEvent{}
+	for rows.Next() {
+		var event models.AdjustmentEvent
+		err = rows.Scan(&event.Id, &event.AdjustmentId, &event.Action, &event.Client, &event.Comment,
+			&event.CreatedAt)
+		if err != nil {
+			return models.Adjustment{}, fmt.Errorf("adjustment event row scan failed: %w", err)
+		}
+		adjustment.Events = append(adjustment.Events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return models.Adjustment{}, fmt.Errorf("adjustment event rows iteration failed: %w", err)
+	}
+	return adjustment, nil
+}
+
+func (db *Database) ListAdjustments(ctx context.Context, tenant string,
+	filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	rows, err := db.DB.Query(ctx,
+		"SELECT "+adjustmentColumns+` FROM balance.adjustments
+			WHERE tenant = $1 AND ($2 = 0 OR user_id = $2) AND ($3 = '' OR status = $3)
+			ORDER BY id`,
+		tenant, filter.UserId, filter.Status)
+	if err != nil {
+		return nil, fmt.Errorf("list adjustments query failed: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := []models.Adjustment{}
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("adjustment row scan failed: %w", err)
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("adjustment rows iteration failed: %w", err)
+	}
+	return adjustments, nil
+}
+
+func (db *Database) TransitionAdjustment(ctx context.Context, tenant string, id int64, from string,
+	event models.AdjustmentEvent) (models.Adjustment, error) {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx,
+		"SELECT status FROM balance.adjustments WHERE tenant = $1 AND id = $2 FOR UPDATE", tenant, id).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d: %w", id, errors.UnknownAdjustmentError)
+	}
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("lock adjustment query row failed: %w", err)
+	}
+	if status != from {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d is %s: %w", id, status,
+			errors.AdjustmentNotPendingError)
+	}
+
+	decides := event.Action == models.AdjustmentApproved || event.Action == models.AdjustmentRejected ||
+		event.Action == models.AdjustmentFailed
+	adjustment, err := scanAdjustment(tx.QueryRow(ctx,
+		`UPDATE balance.adjustments
+			SET status = $2,
+				decided_by = CASE WHEN $3 THEN $4 ELSE decided_by END,
+				decided_at = CASE WHEN $3 THEN $5 ELSE decided_at END
+			WHERE id = $1
+			RETURNING `+adjustmentColumns,
+		id, event.Action, decides, event.Client, event.CreatedAt))
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("update adjustment query row failed: %w", err)
+	}
+
+	event.AdjustmentId = id
+	if err = insertAdjustmentEvent(ctx, tx, tenant, event); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Adjustment{}, fmt.Errorf("tx commit failed: %w", err)
+	}
+	return adjustment, nil
+}
+
+func (db *Database) PostAdjustment(ctx context.Context, tenant string, id int64, event models.AdjustmentEvent,
+	post func(tx ports.Tx, adjustment models.Adjustment) error) (models.Adjustment, error) {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	adjustment, err := scanAdjustment(tx.QueryRow(ctx,
+		"SELECT "+adjustmentColumns+" FROM balance.adjustments WHERE tenant = $1 AND id = $2 FOR UPDATE",
+		tenant, id))
+	if err == pgx.ErrNoRows {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d: %w", id, errors.UnknownAdjustmentError)
+	}
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("lock adjustment query row failed: %w", err)
+	}
+	if adjustment.Status != models.AdjustmentPending {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d is %s: %w", id, adjustment.Status,
+			errors.AdjustmentNotPendingError)
+	}
+
+	if err = post(tx, adjustment); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	adjustment, err = scanAdjustment(tx.QueryRow(ctx,
+		`UPDATE balance.adjustments
+			SET status = $2, decided_by = $3, decided_at = $4
+			WHERE id = $1
+			RETURNING `+adjustmentColumns,
+		id, models.AdjustmentPosted, event.Client, event.CreatedAt))
+	if err != nil {
+		return models.Adjustment{}, fmt.Errorf("update adjustment query row failed: %w", err)
+	}
+
+	event.AdjustmentId = id
+	for _, action := range []string{models.AdjustmentApproved, models.AdjustmentPosted} {
+		event.Action = action
+		if err = insertAdjustmentEvent(ctx, tx, tenant, event); err != nil {
+			return models.Adjustment{}, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Adjustment{}, fmt.Errorf("tx commit failed: %w", err)
+	}
+	return adjustment, nil
+}
+
+func (db *Database) ExpireAdjustments(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := db.DB.Exec(ctx,
+		`WITH expired AS (
+				UPDATE balance.adjustments
+				SET status = 'expired'
+				WHERE status = 'pending' AND expires_at <= $1
+				RETURNING id, tenant
+			)
+			INSERT INTO balance.adjustment_events (tenant, adjustment_id, action, created_at)
+			SELECT tenant, id, 'expired', $1 FROM expired`,
+		now)
+	if err != nil {
+		return 0, fmt.Errorf("expire adjustments query exec failed: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func insertAdjustmentEvent(ctx context.Context, tx pgx.Tx, tenant string, event models.AdjustmentEvent) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO balance.adjustment_events
+				(tenant, adjustment_id, action, client, comment, created_at)
+			VALUES
+				($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)`,
+		tenant, event.AdjustmentId, event.Action, event.Client, event.Comment, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("add adjustment event query exec failed: %w", err)
+	}
+	return nil
+}
+
+func scanAdjustment(row pgx.Row) (models.Adjustment, error) {
+	var adjustment models.Adjustment
+	var value string
+	err := row.Scan(&adjustment.Id, &adjustment.UserId, &value, &adjustment.Reason, &adjustment.AttachmentRef,
+		&adjustment.Status, &adjustment.ProposedBy, &adjustment.DecidedBy, &adjustment.CreatedAt,
+		&adjustment.ExpiresAt, &adjustment.DecidedAt)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	if adjustment.Value, err = decimal.NewFromString(value); err != nil {
+		return models.Adjustment{}, fmt.Errorf("cannot get decimal value from string %v", value)
+	}
+	return adjustment, nil
+}
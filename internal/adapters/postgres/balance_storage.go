@@ -3,6 +3,7 @@ package postgres
 import (
 	"balance/internal/domain/errors"
 	"balance/internal/domain/models"
+	"balance/internal/ports"
 	"context"
 	"fmt"
 	"github.com/jackc/pgconn"
@@ -11,19 +12,85 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-func (db *Database) AddIncome(ctx context.Context, income models.BalanceWithDesc) error {
+func (db *Database) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	tag, err := db.DB.Exec(ctx,
+		`INSERT INTO balance.balance
+				(tenant, user_id, value, type, owner_ref, created_at)
+			VALUES
+				($1, $2, 0, $3, NULLIF($4, ''), $5)
+			ON CONFLICT (tenant, user_id) DO NOTHING`,
+		tenant, account.Id, account.Type, account.OwnerRef, account.CreatedAt)
+	if err != nil {
+		return models.Account{}, fmt.Errorf("create account query exec failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.Account{}, fmt.Errorf("user_id %d: %w", account.Id, errors.AccountExistsError)
+	}
+	return account, nil
+}
+
+func (db *Database) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	var account models.Account
+	err := db.DB.QueryRow(ctx,
+		`SELECT user_id, type, COALESCE(owner_ref, ''), created_at
+			FROM balance.balance
+			WHERE tenant = $1 AND user_id = $2`,
+		tenant, id).Scan(&account.Id, &account.Type, &account.OwnerRef, &account.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return models.Account{}, fmt.Errorf("user_id %d: %w", id, errors.UnknownUserIdError)
+	}
+	if err != nil {
+		return models.Account{}, fmt.Errorf("get account query row failed: %w", err)
+	}
+	return account, nil
+}
+
+func (db *Database) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
 	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return fmt.Errorf("begin tx failed: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx,
+	if err = addIncome(ctx, tx, tenant, income); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx commit failed failed: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	income models.BalanceWithDesc) error {
+	pgTx, err := ownTx(tx)
+	if err != nil {
+		return err
+	}
+	return addIncome(ctx, pgTx, tenant, income)
+}
+
+// ownTx returns the transaction opened by this storage.
+func ownTx(tx ports.Tx) (pgx.Tx, error) {
+	pgTx, ok := tx.(pgx.Tx)
+	if !ok {
+		return nil, fmt.Errorf("unexpected transaction type %T", tx)
+	}
+	return pgTx, nil
+}
+
+func addIncome(ctx context.Context, tx pgx.Tx, tenant string, income models.BalanceWithDesc) error {
+	var historyId int64
+
+	err := tx.QueryRow(ctx,
 		`INSERT INTO balance.history
-				(from_id, to_id, value, occurred_at, description)
+				(tenant, from_id, to_id, value, occurred_at, description, client)
 			VALUES
-				($1, $2, $3, $4, $5)`,
-		0, income.UserId, income.Value, income.Time, income.Description)
+				($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+			RETURNING id`,
+		tenant, 0, income.UserId, income.Value, income.Time, income.Description, income.Client).Scan(&historyId)
 
 	if err != nil {
 		return fmt.Errorf("add transaction to history query exec failed: %w", err)
@@ -32,47 +99,76 @@ func (db *Database) AddIncome(ctx context.Context, income models.BalanceWithDesc
 	var isUserIdExist bool
 
 	err = tx.QueryRow(ctx,
-		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE user_id = $1) AS exists",
-		income.UserId).Scan(&isUserIdExist)
+		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE tenant = $1 AND user_id = $2) AS exists",
+		tenant, income.UserId).Scan(&isUserIdExist)
 
 	if err != nil {
 		return fmt.Errorf("check user_id exists query row failed: %w", err)
 	}
 	if isUserIdExist {
+		if err = checkNotFrozen(ctx, tx, tenant, income.UserId); err != nil {
+			return err
+		}
 		_, err = tx.Exec(ctx,
-			"UPDATE balance.balance SET value = value + $1 WHERE user_id = $2",
-			income.Value, income.UserId)
+			"UPDATE balance.balance SET value = value + $1 WHERE tenant = $2 AND user_id = $3",
+			income.Value, tenant, income.UserId)
 		if err != nil {
 			return fmt.Errorf("add income query exec failed: %w", err)
 		}
 	} else {
 		_, err = tx.Exec(ctx,
-			"INSERT INTO balance.balance (user_id, value) VALUES($1, $2)",
-			income.UserId, income.Value)
+			"INSERT INTO balance.balance (tenant, user_id, value) VALUES($1, $2, $3)",
+			tenant, income.UserId, income.Value)
 		if err != nil {
 			return fmt.Errorf("add new user_id with balance query exec failed: %w", err)
 		}
 	}
 
+	return insertEvent(ctx, tx, tenant, models.BalanceCredited, models.BalanceChangedPayload{
+		HistoryId:   historyId,
+		UserId:      income.UserId,
+		Value:       income.Value,
+		Description: income.Description,
+		OccurredAt:  income.Time,
+	})
+}
+
+func (db *Database) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = addExpense(ctx, tx, tenant, expense); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("tx commit failed failed: %w", err)
 	}
 	return nil
 }
 
-func (db *Database) AddExpense(ctx context.Context, expense models.BalanceWithDesc) error {
-	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+func (db *Database) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	pgTx, err := ownTx(tx)
 	if err != nil {
-		return fmt.Errorf("begin tx failed: %w", err)
+		return err
 	}
-	defer tx.Rollback(ctx)
+	return addExpense(ctx, pgTx, tenant, expense)
+}
 
-	_, err = tx.Exec(ctx,
+func addExpense(ctx context.Context, tx pgx.Tx, tenant string, expense models.BalanceWithDesc) error {
+	var historyId int64
+
+	err := tx.QueryRow(ctx,
 		`INSERT INTO balance.history
-				(from_id, to_id, value, occurred_at, description)
+				(tenant, from_id, to_id, value, occurred_at, description, client)
 			VALUES
-				($1, $2, $3, $4, $5)`,
-		expense.UserId, 0, expense.Value, expense.Time, expense.Description)
+				($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+			RETURNING id`,
+		tenant, expense.UserId, 0, expense.Value, expense.Time, expense.Description, expense.Client).Scan(&historyId)
 	if err != nil {
 		return fmt.Errorf("add transaction to history query exec failed: %w", err)
 	}
@@ -80,16 +176,19 @@ func (db *Database) AddExpense(ctx context.Context, expense models.BalanceWithDe
 	var isUserIdExist bool
 
 	err = tx.QueryRow(ctx,
-		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE user_id = $1) AS exists",
-		expense.UserId).Scan(&isUserIdExist)
+		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE tenant = $1 AND user_id = $2) AS exists",
+		tenant, expense.UserId).Scan(&isUserIdExist)
 
 	if err != nil {
 		return fmt.Errorf("check user_id exists query row failed: %w", err)
 	}
 	if isUserIdExist {
+		if err = checkNotFrozen(ctx, tx, tenant, expense.UserId); err != nil {
+			return err
+		}
 		_, err = tx.Exec(ctx,
-			"UPDATE balance.balance SET value = value - $1 WHERE user_id = $2",
-			expense.Value, expense.UserId)
+			"UPDATE balance.balance SET value = value - $1 WHERE tenant = $2 AND user_id = $3",
+			expense.Value, tenant, expense.UserId)
 		if err != nil {
 
 			if errPq, ok := err.(*pgconn.PgError); ok {
@@ -104,43 +203,71 @@ func (db *Database) AddExpense(ctx context.Context, expense models.BalanceWithDe
 		return fmt.Errorf("user_id %d: %w", expense.UserId, errors.UnknownUserIdError)
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("tx commit failed failed: %w", err)
-	}
-	return nil
+	return insertEvent(ctx, tx, tenant, models.BalanceDebited, models.BalanceChangedPayload{
+		HistoryId:   historyId,
+		UserId:      expense.UserId,
+		Value:       expense.Value,
+		Description: expense.Description,
+		OccurredAt:  expense.Time,
+	})
 }
 
-func (db *Database) DoTransfer(ctx context.Context, transaction models.Transaction) error {
+func (db *Database) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
 	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return fmt.Errorf("begin tx failed: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx,
+	var historyId int64
+
+	err = tx.QueryRow(ctx,
 		`INSERT INTO balance.history
-				(from_id, to_id, value, occurred_at, description)
+				(tenant, from_id, to_id, value, occurred_at, description, client)
 			VALUES
-				($1, $2, $3, $4, $5)`,
-		transaction.UserIdFrom, transaction.UserIdTo, transaction.Value,
-		transaction.Time, transaction.Description)
+				($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+			RETURNING id`,
+		tenant, transaction.UserIdFrom, transaction.UserIdTo, transaction.Value,
+		transaction.Time, transaction.Description, transaction.Client).Scan(&historyId)
 	if err != nil {
 		return fmt.Errorf("add transaction to history query exec failed: %w", err)
 	}
 
+	// lock both accounts in a fixed order so opposite transfers cannot deadlock
+	types, err := lockAccounts(ctx, tx, tenant, transaction.UserIdFrom, transaction.UserIdTo)
+	if err != nil {
+		return err
+	}
+	if fromType, ok := types[transaction.UserIdFrom]; ok && transaction.Rules != nil {
+		// a missing receiving account is created as a user account
+		toType, ok := types[transaction.UserIdTo]
+		if !ok {
+			toType = models.AccountUser
+		}
+		if !transaction.Rules.Allows(fromType, toType) {
+			return fmt.Errorf("%s to %s: %w", fromType, toType, errors.TransferNotAllowedError)
+		}
+	}
+
 	var isUserIdFromExist bool
 
 	err = tx.QueryRow(ctx,
-		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE user_id = $1) AS exists",
-		transaction.UserIdFrom).Scan(&isUserIdFromExist)
+		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE tenant = $1 AND user_id = $2) AS exists",
+		tenant, transaction.UserIdFrom).Scan(&isUserIdFromExist)
 
 	if err != nil {
 		return fmt.Errorf("check user_id exists query row failed: %w", err)
 	}
 	if isUserIdFromExist {
+		if err = checkNotFrozen(ctx, tx, tenant, transaction.UserIdFrom); err != nil {
+			return err
+		}
+		if err = checkNotFrozen(ctx, tx, tenant, transaction.UserIdTo); err != nil {
+			return err
+		}
 		_, err = tx.Exec(ctx,
-			"UPDATE balance.balance SET value = value - $1 WHERE user_id = $2",
-			transaction.Value, transaction.UserIdFrom)
+			"UPDATE balance.balance SET value = value - $1 WHERE tenant = $2 AND user_id = $3",
+			transaction.Value, tenant, transaction.UserIdFrom)
 		if err != nil {
 
 			if errPq, ok := err.(*pgconn.PgError); ok {
@@ -158,47 +285,88 @@ func (db *Database) DoTransfer(ctx context.Context, transaction models.Transacti
 	var isUserIdToExist bool
 
 	err = tx.QueryRow(ctx,
-		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE user_id = $1) AS exists",
-		transaction.UserIdTo).Scan(&isUserIdToExist)
+		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE tenant = $1 AND user_id = $2) AS exists",
+		tenant, transaction.UserIdTo).Scan(&isUserIdToExist)
 
 	if err != nil {
 		return fmt.Errorf("check user_id exists query row failed: %w", err)
 	}
 	if isUserIdToExist {
 		_, err = tx.Exec(ctx,
-			"UPDATE balance.balance SET value = value + $1 WHERE user_id = $2",
-			transaction.Value, transaction.UserIdTo)
+			"UPDATE balance.balance SET value = value + $1 WHERE tenant = $2 AND user_id = $3",
+			transaction.Value, tenant, transaction.UserIdTo)
 		if err != nil {
 			return fmt.Errorf("add income query exec failed: %v", err)
 		}
 	} else {
 		_, err = tx.Exec(ctx,
-			"INSERT INTO balance.balance (user_id, value) VALUES($1, $2)",
-			transaction.UserIdTo, transaction.Value)
+			"INSERT INTO balance.balance (tenant, user_id, value) VALUES($1, $2, $3)",
+			tenant, transaction.UserIdTo, transaction.Value)
 		if err != nil {
 			return fmt.Errorf("create new user_id with balance query exec failed: %w", err)
 		}
 	}
 
+	err = insertEvent(ctx, tx, tenant, models.TransferCompleted, models.TransferCompletedPayload{
+		HistoryId:   historyId,
+		UserIdFrom:  transaction.UserIdFrom,
+		UserIdTo:    transaction.UserIdTo,
+		Value:       transaction.Value,
+		Description: transaction.Description,
+		OccurredAt:  transaction.Time,
+	})
+	if err != nil {
+		return err
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("tx commit failed failed: %w", err)
 	}
 	return nil
 }
 
-func (db *Database) GetBalance(ctx context.Context, userId int64) (models.Balance, error) {
+// lockAccounts locks the existing accounts among ids for update in the order
+// of their ids and returns their types.
+func lockAccounts(ctx context.Context, tx pgx.Tx, tenant string,
+	ids ...int64) (map[int64]models.AccountType, error) {
+	rows, err := tx.Query(ctx,
+		"SELECT user_id, type FROM balance.balance WHERE tenant = $1 AND user_id = ANY($2) ORDER BY user_id FOR UPDATE",
+		tenant, ids)
+	if err != nil {
+		return nil, fmt.Errorf("lock balances query failed: %w", err)
+	}
+	defer rows.Close()
+
+	types := map[int64]models.AccountType{}
+	for rows.Next() {
+		var id int64
+		var accountType models.AccountType
+		if err := rows.Scan(&id, &accountType); err != nil {
+			return nil, fmt.Errorf("lock balances scan failed: %w", err)
+		}
+		types[id] = accountType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lock balances query failed: %w", err)
+	}
+	return types, nil
+}
+
+func (db *Database) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
 	var balanceValue string
 	var isUserIdExist bool
+	var frozen bool
 
 	err := db.DB.QueryRow(ctx,
-		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE user_id = $1) AS exists",
-		userId).Scan(&isUserIdExist)
+		"SELECT EXISTS(SELECT user_id FROM balance.balance WHERE tenant = $1 AND user_id = $2) AS exists",
+		tenant, userId).Scan(&isUserIdExist)
 	if err != nil {
 		return models.Balance{}, fmt.Errorf("check user_id exists query row failed: %w", err)
 	}
 	if isUserIdExist {
 		err = db.DB.QueryRow(ctx,
-			"SELECT value FROM balance.balance WHERE user_id = $1", userId).Scan(&balanceValue)
+			"SELECT value, frozen FROM balance.balance WHERE tenant = $1 AND user_id = $2",
+			tenant, userId).Scan(&balanceValue, &frozen)
 		if err != nil {
 			return models.Balance{}, fmt.Errorf("get balance query row failed: %w", err)
 		}
@@ -210,6 +378,87 @@ func (db *Database) GetBalance(ctx context.Context, userId int64) (models.Balanc
 	if balErr != nil {
 		return models.Balance{}, fmt.Errorf("cannot get decimal balance from string %v", balanceValue)
 	}
-	balance := models.Balance{UserId: userId, Value: balanceDecimal}
+	balance := models.Balance{UserId: userId, Value: balanceDecimal, Frozen: frozen}
 	return balance, nil
 }
+
+// checkNotFrozen locks the account row for the rest of tx, so it cannot be
+// frozen between the check and the update. A missing account is not frozen.
+func checkNotFrozen(ctx context.Context, tx pgx.Tx, tenant string, userId int64) error {
+	var frozen bool
+	err := tx.QueryRow(ctx,
+		"SELECT frozen FROM balance.balance WHERE tenant = $1 AND user_id = $2 FOR UPDATE",
+		tenant, userId).Scan(&frozen)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check frozen query row failed: %w", err)
+	}
+	if frozen {
+		return fmt.Errorf("user_id %d: %w", userId, errors.AccountFrozenError)
+	}
+	return nil
+}
+
+func (db *Database) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	tag, err := db.DB.Exec(ctx,
+		"UPDATE balance.balance SET frozen = $1 WHERE tenant = $2 AND user_id = $3", frozen, tenant, userId)
+	if err != nil {
+		return fmt.Errorf("set frozen query exec failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user_id %d: %w", userId, errors.UnknownUserIdError)
+	}
+	return nil
+}
+
+func (db *Database) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	reconciliation := models.Reconciliation{Mismatches: []models.ReconciliationMismatch{}}
+
+	err := db.DB.QueryRow(ctx,
+		"SELECT count(*) FROM balance.balance WHERE tenant = $1", tenant).Scan(&reconciliation.Accounts)
+	if err != nil {
+		return models.Reconciliation{}, fmt.Errorf("count balances query row failed: %w", err)
+	}
+
+	rows, err := db.DB.Query(ctx,
+		`WITH movements AS (
+				SELECT to_id AS user_id, value FROM balance.history WHERE tenant = $1
+				UNION ALL
+				SELECT from_id, -value FROM balance.history WHERE tenant = $1
+			), totals AS (
+				SELECT user_id, SUM(value) AS value FROM movements WHERE user_id <> 0 GROUP BY user_id
+			), balances AS (
+				SELECT user_id, value FROM balance.balance WHERE tenant = $1
+			)
+			SELECT COALESCE(b.user_id, t.user_id), COALESCE(b.value, 0)::text, COALESCE(t.value, 0)::text
+			FROM balances b
+				FULL JOIN totals t ON t.user_id = b.user_id
+			WHERE COALESCE(b.value, 0) <> COALESCE(t.value, 0)
+			ORDER BY 1`,
+		tenant)
+	if err != nil {
+		return models.Reconciliation{}, fmt.Errorf("reconcile query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mismatch models.ReconciliationMismatch
+		var balanceValue, historyValue string
+		if err = rows.Scan(&mismatch.UserId, &balanceValue, &historyValue); err != nil {
+			return models.Reconciliation{}, fmt.Errorf("reconcile row scan failed: %w", err)
+		}
+		if mismatch.Balance, err = decimal.NewFromString(balanceValue); err != nil {
+			return models.Reconciliation{}, fmt.Errorf("cannot get decimal balance from string %v", balanceValue)
+		}
+		if mismatch.HistoryBalance, err = decimal.NewFromString(historyValue); err != nil {
+			return models.Reconciliation{}, fmt.Errorf("cannot get decimal balance from string %v", historyValue)
+		}
+		reconciliation.Mismatches = append(reconciliation.Mismatches, mismatch)
+	}
+	if err = rows.Err(); err != nil {
+		return models.Reconciliation{}, fmt.Errorf("reconcile rows iteration failed: %w", err)
+	}
+	return reconciliation, nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+)
+
+func (db *Database) CreateClient(ctx context.Context, client models.Client, keyHash string) (models.Client, error) {
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+	err := db.DB.QueryRow(ctx,
+		`INSERT INTO balance.clients
+				(name, tenant, key_hash, scopes, created_at)
+			VALUES
+				($1, NULLIF($2, ''), $3, $4, $5)
+			RETURNING id`,
+		client.Name, client.Tenant, keyHash, client.Scopes, client.CreatedAt).Scan(&client.Id)
+	if err != nil {
+		return models.Client{}, fmt.Errorf("create client query row failed: %w", err)
+	}
+	return client, nil
+}
+
+func (db *Database) GetClientByKeyHash(ctx context.Context, keyHash string) (models.Client, error) {
+	var client models.Client
+
+	err := db.DB.QueryRow(ctx,
+		"SELECT id, name, COALESCE(tenant, ''), scopes, created_at FROM balance.clients WHERE key_hash = $1",
+		keyHash).Scan(&client.Id, &client.Name, &client.Tenant, &client.Scopes, &client.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return models.Client{}, errors.UnknownClientError
+	}
+	if err != nil {
+		return models.Client{}, fmt.Errorf("get client query row failed: %w", err)
+	}
+	return client, nil
+}
+
+// ListClients lists the clients of the tenant, or all clients when tenant is
+// empty.
+func (db *Database) ListClients(ctx context.Context, tenant string) ([]models.Client, error) {
+	rows, err := db.DB.Query(ctx,
+		`SELECT id, name, COALESCE(tenant, ''), scopes, created_at
+			FROM balance.clients
+			WHERE $1 = '' OR tenant = $1
+			ORDER BY id`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("list clients query failed: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []models.Client{}
+	for rows.Next() {
+		var client models.Client
+		if err = rows.Scan(&client.Id, &client.Name, &client.Tenant, &client.Scopes, &client.CreatedAt); err != nil {
+			return nil, fmt.Errorf("client row scan failed: %w", err)
+		}
+		clients = append(clients, client)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("client rows iteration failed: %w", err)
+	}
+	return clients, nil
+}
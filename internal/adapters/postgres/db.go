@@ -3,18 +3,37 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"strconv"
 )
 
 type Database struct {
 	DB *pgxpool.Pool
 }
 
-func New(ctx context.Context, pgconn string) (*Database, error) {
+func New(ctx context.Context, pgconn string, options PoolOptions) (*Database, error) {
 	config, err := pgxpool.ParseConfig(pgconn)
 	if err != nil {
 		return nil, fmt.Errorf("postgres connection string parse failed: %v", err)
 	}
+	config.ConnConfig.Logger = queryLogger{}
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+	if options.MaxConns > 0 {
+		config.MaxConns = options.MaxConns
+	}
+	if options.MinConns > 0 {
+		config.MinConns = options.MinConns
+	}
+	if options.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = options.MaxConnLifetime
+	}
+	if options.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = options.MaxConnIdleTime
+	}
+	if options.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(options.StatementTimeout.Milliseconds(), 10)
+	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
@@ -23,3 +42,7 @@ func New(ctx context.Context, pgconn string) (*Database, error) {
 
 	return &Database{DB: pool}, nil
 }
+
+func (db *Database) Close() {
+	db.DB.Close()
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+func (db *Database) Ping(ctx context.Context) error {
+	if err := db.DB.Ping(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) MigrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := db.DB.QueryRow(ctx,
+		`SELECT COALESCE(max(version_id), 0)
+			FROM (SELECT DISTINCT ON (version_id) version_id, is_applied
+				FROM goose_db_version
+				ORDER BY version_id, id DESC) AS versions
+			WHERE is_applied`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("get migration version query row failed: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// querier runs reads on the pool or inside a transaction
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+func (db *Database) GetBalanceAt(ctx context.Context, tenant string, userId int64,
+	at time.Time) (decimal.Decimal, error) {
+	return balanceAt(ctx, db.DB, tenant, userId, at)
+}
+
+func balanceAt(ctx context.Context, q querier, tenant string, userId int64, at time.Time) (decimal.Decimal, error) {
+	var balanceValue string
+
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(
+				SUM(CASE WHEN to_id = $2 THEN value ELSE 0 END) -
+				SUM(CASE WHEN from_id = $2 THEN value ELSE 0 END), 0)::text
+			FROM balance.history
+			WHERE tenant = $1 AND (from_id = $2 OR to_id = $2) AND occurred_at < $3`,
+		tenant, userId, at).Scan(&balanceValue)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("get balance at query row failed: %w", err)
+	}
+
+	balanceDecimal, err := decimal.NewFromString(balanceValue)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("cannot get decimal balance from string %v", balanceValue)
+	}
+	return balanceDecimal, nil
+}
+
+func (db *Database) GetHistory(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	fn func(models.Transaction) error) error {
+	return history(ctx, db.DB, tenant, userId, from, to, fn)
+}
+
+func history(ctx context.Context, q querier, tenant string, userId int64, from, to time.Time,
+	fn func(models.Transaction) error) error {
+	rows, err := q.Query(ctx,
+		`SELECT id, from_id, to_id, COALESCE(value, 0)::text, occurred_at, COALESCE(description, ''),
+				COALESCE(client, '')
+			FROM balance.history
+			WHERE tenant = $1 AND (from_id = $2 OR to_id = $2) AND occurred_at >= $3 AND occurred_at < $4
+			ORDER BY occurred_at, id`,
+		tenant, userId, from, to)
+	if err != nil {
+		return fmt.Errorf("get history query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		var value string
+
+		err = rows.Scan(&transaction.Id, &transaction.UserIdFrom, &transaction.UserIdTo, &value,
+			&transaction.Time, &transaction.Description, &transaction.Client)
+		if err != nil {
+			return fmt.Errorf("history row scan failed: %w", err)
+		}
+		transaction.Value, err = decimal.NewFromString(value)
+		if err != nil {
+			return fmt.Errorf("cannot get decimal value from string %v", value)
+		}
+
+		if err = fn(transaction); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("history rows iteration failed: %w", err)
+	}
+	return nil
+}
+
+// GetStatement reads in a REPEATABLE READ transaction, so transactions
+// committed while the statement is streamed are not part of it.
+func (db *Database) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	header func(opening, closing decimal.Decimal) error, fn func(models.Transaction) error) error {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	opening, err := balanceAt(ctx, tx, tenant, userId, from)
+	if err != nil {
+		return err
+	}
+	closing, err := balanceAt(ctx, tx, tenant, userId, to)
+	if err != nil {
+		return err
+	}
+	if err = header(opening, closing); err != nil {
+		return err
+	}
+	return history(ctx, tx, tenant, userId, from, to, fn)
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+type Options struct {
+	User        string
+	Password    string
+	Host        string
+	Port        string
+	Database    string
+	SslMode     string
+	SslRootCert string
+	SslCert     string
+	SslKey      string
+}
+
+func (o Options) ConnString() string {
+	query := url.Values{}
+	sslMode := o.SslMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	query.Set("sslmode", sslMode)
+	if o.SslRootCert != "" {
+		query.Set("sslrootcert", o.SslRootCert)
+	}
+	if o.SslCert != "" {
+		query.Set("sslcert", o.SslCert)
+	}
+	if o.SslKey != "" {
+		query.Set("sslkey", o.SslKey)
+	}
+
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(o.User, o.Password),
+		Host:     net.JoinHostPort(o.Host, o.Port),
+		Path:     "/" + o.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// PoolOptions tunes the connection pool, zero values keep the pgxpool
+// defaults. StatementTimeout is applied as the statement_timeout of every
+// connection.
+type PoolOptions struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration
+	MaxConnIdleTime  time.Duration
+	StatementTimeout time.Duration
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+func insertEvent(ctx context.Context, tx pgx.Tx, tenant string, eventType models.EventType,
+	payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event payload failed: %w", eventType, err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO balance.outbox (tenant, event_type, payload, created_at) VALUES ($1, $2, $3, $4)",
+		tenant, string(eventType), body, time.Now())
+	if err != nil {
+		return fmt.Errorf("add %s event to outbox query exec failed: %w", eventType, err)
+	}
+	return nil
+}
+
+func (db *Database) ProcessEvents(ctx context.Context, limit int, fn func(models.Event) error) (int, error) {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, tenant, event_type, payload, created_at
+			FROM balance.outbox
+			WHERE delivered_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`,
+		limit)
+	if err != nil {
+		return 0, fmt.Errorf("get undelivered events query failed: %w", err)
+	}
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var eventType string
+		var payload []byte
+
+		if err = rows.Scan(&event.Id, &event.Tenant, &eventType, &payload, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("event row scan failed: %w", err)
+		}
+		event.Type = models.EventType(eventType)
+		event.Payload = payload
+		events = append(events, event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("event rows iteration failed: %w", err)
+	}
+
+	delivered := 0
+	var fnErr error
+	for _, event := range events {
+		if fnErr = fn(event); fnErr != nil {
+			break
+		}
+		_, err = tx.Exec(ctx, "UPDATE balance.outbox SET delivered_at = $1 WHERE id = $2", time.Now(), event.Id)
+		if err != nil {
+			return 0, fmt.Errorf("mark event delivered query exec failed: %w", err)
+		}
+		delivered++
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("tx commit failed failed: %w", err)
+	}
+	return delivered, fnErr
+}
+
+func (db *Database) GetOutboxStats(ctx context.Context) (models.OutboxStats, error) {
+	var stats models.OutboxStats
+	var oldest *time.Time
+	err := db.DB.QueryRow(ctx,
+		"SELECT count(*), min(created_at) FROM balance.outbox WHERE delivered_at IS NULL").
+		Scan(&stats.Pending, &oldest)
+	if err != nil {
+		return models.OutboxStats{}, fmt.Errorf("get outbox stats query row failed: %w", err)
+	}
+	if oldest != nil {
+		stats.OldestPending = *oldest
+	}
+	return stats, nil
+}
@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"balance/internal/domain/models"
+)
+
+func (db *Database) PoolStats() models.PoolStats {
+	stat := db.DB.Stat()
+	return models.PoolStats{
+		AcquireCount:    stat.AcquireCount(),
+		AcquireDuration: stat.AcquireDuration(),
+		AcquiredConns:   stat.AcquiredConns(),
+		IdleConns:       stat.IdleConns(),
+		TotalConns:      stat.TotalConns(),
+		MaxConns:        stat.MaxConns(),
+	}
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"balance/internal/utils"
+	"context"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"time"
+)
+
+const tracerName = "balance/internal/adapters/postgres"
+
+var nopLogger = zap.NewNop().Sugar()
+
+// queryLogger turns pgx query logs into spans and request-scoped log lines.
+// pgx v4 logs a query once it has finished, so the span start is
+// reconstructed from the reported duration. Query arguments are never
+// recorded as they carry descriptions and other user data.
+type queryLogger struct{}
+
+func (queryLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	sql, ok := data["sql"].(string)
+	if !ok {
+		return
+	}
+	duration, _ := data["time"].(time.Duration)
+	err, _ := data["err"].(error)
+
+	logger := utils.Logger(ctx, nopLogger)
+	if err != nil {
+		logger.Warnw("query failed", "query", msg, "sql", sql, "duration", duration, "error", err)
+	} else {
+		logger.Debugw("query", "query", msg, "sql", sql, "duration", duration)
+	}
+
+	traceQuery(ctx, msg, sql, duration, data, err)
+}
+
+func traceQuery(ctx context.Context, msg, sql string, duration time.Duration, data map[string]interface{},
+	err error) {
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+
+	end := time.Now()
+	start := end.Add(-duration)
+
+	_, span := otel.Tracer(tracerName).Start(ctx, "pgx."+msg,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatementKey.String(sql),
+		))
+	if rowCount, ok := data["rowCount"].(int); ok {
+		span.SetAttributes(attribute.Int("db.row_count", rowCount))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+)
+
+func eventTypesToStrings(eventTypes []models.EventType) []string {
+	values := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		values = append(values, string(eventType))
+	}
+	return values
+}
+
+func eventTypesFromStrings(values []string) []models.EventType {
+	eventTypes := make([]models.EventType, 0, len(values))
+	for _, value := range values {
+		eventTypes = append(eventTypes, models.EventType(value))
+	}
+	return eventTypes
+}
+
+func (db *Database) CreateSubscription(ctx context.Context, tenant string,
+	subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	err := db.DB.QueryRow(ctx,
+		`INSERT INTO balance.webhook_subscriptions
+				(tenant, url, secret, event_types, user_id, created_at)
+			VALUES
+				($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+		tenant, subscription.Url, subscription.Secret, eventTypesToStrings(subscription.EventTypes),
+		subscription.UserId, subscription.CreatedAt).Scan(&subscription.Id)
+	if err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("create webhook subscription query row failed: %w", err)
+	}
+	return subscription, nil
+}
+
+func scanSubscription(row pgx.Row) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	var eventTypes []string
+
+	err := row.Scan(&subscription.Id, &subscription.Url, &subscription.Secret, &eventTypes,
+		&subscription.UserId, &subscription.CreatedAt)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	subscription.EventTypes = eventTypesFromStrings(eventTypes)
+	return subscription, nil
+}
+
+func (db *Database) GetSubscription(ctx context.Context, tenant string,
+	id int64) (models.WebhookSubscription, error) {
+	subscription, err := scanSubscription(db.DB.QueryRow(ctx,
+		`SELECT id, url, secret, event_types, user_id, created_at
+			FROM balance.webhook_subscriptions
+			WHERE tenant = $1 AND id = $2`, tenant, id))
+	if err == pgx.ErrNoRows {
+		return models.WebhookSubscription{}, fmt.Errorf("subscription %d: %w", id, errors.UnknownWebhookError)
+	}
+	if err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("get webhook subscription query row failed: %w", err)
+	}
+	return subscription, nil
+}
+
+func (db *Database) ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error) {
+	rows, err := db.DB.Query(ctx,
+		`SELECT id, url, secret, event_types, user_id, created_at
+			FROM balance.webhook_subscriptions
+			WHERE tenant = $1
+			ORDER BY id`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions query failed: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("webhook subscription row scan failed: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("webhook subscription rows iteration failed: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (db *Database) DeleteSubscription(ctx context.Context, tenant string, id int64) error {
+	tag, err := db.DB.Exec(ctx, "DELETE FROM balance.webhook_subscriptions WHERE tenant = $1 AND id = $2", tenant, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription query exec failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("subscription %d: %w", id, errors.UnknownWebhookError)
+	}
+	return nil
+}
+
+func (db *Database) AddDeliveries(ctx context.Context, tenant string, deliveries []models.WebhookDelivery) error {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, delivery := range deliveries {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO balance.webhook_deliveries
+					(tenant, subscription_id, event_id, event_type, payload, created_at, next_attempt_at)
+				VALUES
+					($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+			tenant, delivery.Subscription.Id, delivery.Event.Id, string(delivery.Event.Type),
+			[]byte(delivery.Event.Payload), delivery.Event.CreatedAt, delivery.NextAttemptAt)
+		if err != nil {
+			return fmt.Errorf("add webhook delivery query exec failed: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx commit failed failed: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) ClaimDeliveries(ctx context.Context, now, lease time.Time,
+	limit int) ([]models.WebhookDelivery, error) {
+	rows, err := db.DB.Query(ctx,
+		`UPDATE balance.webhook_deliveries d
+			SET next_attempt_at = $2
+			FROM balance.webhook_subscriptions s
+			WHERE s.id = d.subscription_id AND d.id IN (
+				SELECT id
+					FROM balance.webhook_deliveries
+					WHERE next_attempt_at <= $1
+					ORDER BY next_attempt_at, id
+					LIMIT $3
+					FOR UPDATE SKIP LOCKED)
+			RETURNING d.id, d.tenant, d.event_id, d.event_type, d.payload, d.created_at, d.attempts,
+				COALESCE(d.last_error, ''), d.next_attempt_at,
+				s.id, s.url, s.secret, s.event_types, s.user_id, s.created_at`,
+		now, lease, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries query failed: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var eventType string
+		var payload []byte
+		var eventTypes []string
+
+		err = rows.Scan(&delivery.Id, &delivery.Event.Tenant, &delivery.Event.Id, &eventType, &payload,
+			&delivery.Event.CreatedAt, &delivery.Attempts, &delivery.LastError, &delivery.NextAttemptAt,
+			&delivery.Subscription.Id, &delivery.Subscription.Url, &delivery.Subscription.Secret, &eventTypes,
+			&delivery.Subscription.UserId, &delivery.Subscription.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("webhook delivery row scan failed: %w", err)
+		}
+		delivery.Event.Type = models.EventType(eventType)
+		delivery.Event.Payload = json.RawMessage(payload)
+		delivery.Subscription.EventTypes = eventTypesFromStrings(eventTypes)
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("webhook delivery rows iteration failed: %w", err)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Id < deliveries[j].Id
+	})
+	return deliveries, nil
+}
+
+func (db *Database) RetryDelivery(ctx context.Context, tenant string, delivery models.WebhookDelivery) error {
+	_, err := db.DB.Exec(ctx,
+		`UPDATE balance.webhook_deliveries
+			SET attempts = $1, last_error = $2, next_attempt_at = $3
+			WHERE tenant = $4 AND id = $5`,
+		delivery.Attempts, delivery.LastError, delivery.NextAttemptAt, tenant, delivery.Id)
+	if err != nil {
+		return fmt.Errorf("retry webhook delivery query exec failed: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) DeleteDelivery(ctx context.Context, tenant string, id int64) error {
+	_, err := db.DB.Exec(ctx, "DELETE FROM balance.webhook_deliveries WHERE tenant = $1 AND id = $2", tenant, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook delivery query exec failed: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) DeadLetterDelivery(ctx context.Context, tenant string, id int64,
+	deadLetter models.DeadLetter) error {
+	tx, err := db.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO balance.webhook_dead_letters
+				(tenant, subscription_id, event_id, event_type, payload, created_at, attempts, last_error, failed_at)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		tenant, deadLetter.SubscriptionId, deadLetter.Event.Id, string(deadLetter.Event.Type),
+		[]byte(deadLetter.Event.Payload), deadLetter.Event.CreatedAt, deadLetter.Attempts, deadLetter.LastError,
+		deadLetter.FailedAt)
+	if err != nil {
+		return fmt.Errorf("add dead letter query exec failed: %w", err)
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM balance.webhook_deliveries WHERE tenant = $1 AND id = $2", tenant, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook delivery query exec failed: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx commit failed failed: %w", err)
+	}
+	return nil
+}
+
+func scanDeadLetter(row pgx.Row) (models.DeadLetter, error) {
+	var deadLetter models.DeadLetter
+	var eventType string
+	var payload []byte
+
+	err := row.Scan(&deadLetter.Id, &deadLetter.SubscriptionId, &deadLetter.Event.Id, &deadLetter.Event.Tenant,
+		&eventType, &payload,
+		&deadLetter.Event.CreatedAt, &deadLetter.Attempts, &deadLetter.LastError, &deadLetter.FailedAt,
+		&deadLetter.ReplayedAt)
+	if err != nil {
+		return models.DeadLetter{}, err
+	}
+	deadLetter.Event.Type = models.EventType(eventType)
+	deadLetter.Event.Payload = json.RawMessage(payload)
+	return deadLetter, nil
+}
+
+func (db *Database) GetDeadLetter(ctx context.Context, tenant string, id int64) (models.DeadLetter, error) {
+	deadLetter, err := scanDeadLetter(db.DB.QueryRow(ctx,
+		`SELECT id, subscription_id, event_id, tenant, event_type, payload, created_at, attempts, last_error,
+				failed_at, replayed_at
+			FROM balance.webhook_dead_letters
+			WHERE tenant = $1 AND id = $2`, tenant, id))
+	if err == pgx.ErrNoRows {
+		return models.DeadLetter{}, fmt.Errorf("dead letter %d: %w", id, errors.UnknownDeadLetterError)
+	}
+	if err != nil {
+		return models.DeadLetter{}, fmt.Errorf("get dead letter query row failed: %w", err)
+	}
+	return deadLetter, nil
+}
+
+func (db *Database) ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error) {
+	rows, err := db.DB.Query(ctx,
+		`SELECT id, subscription_id, event_id, tenant, event_type, payload, created_at, attempts, last_error,
+				failed_at, replayed_at
+			FROM balance.webhook_dead_letters
+			WHERE tenant = $1
+			ORDER BY id`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters query failed: %w", err)
+	}
+	defer rows.Close()
+
+	deadLetters := []models.DeadLetter{}
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("dead letter row scan failed: %w", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("dead letter rows iteration failed: %w", err)
+	}
+	return deadLetters, nil
+}
+
+func (db *Database) UpdateDeadLetter(ctx context.Context, tenant string, deadLetter models.DeadLetter) error {
+	_, err := db.DB.Exec(ctx,
+		`UPDATE balance.webhook_dead_letters
+			SET attempts = $1, last_error = $2, failed_at = $3, replayed_at = $4
+			WHERE tenant = $5 AND id = $6`,
+		deadLetter.Attempts, deadLetter.LastError, deadLetter.FailedAt, deadLetter.ReplayedAt, tenant, deadLetter.Id)
+	if err != nil {
+		return fmt.Errorf("update dead letter query exec failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package publisher
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+)
+
+type Fanout struct {
+	publishers []ports.EventPublisher
+}
+
+func NewFanout(publishers ...ports.EventPublisher) *Fanout {
+	return &Fanout{publishers: publishers}
+}
+
+func (f *Fanout) Publish(ctx context.Context, event models.Event) error {
+	for _, publisher := range f.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type JSONL struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewJSONL(path string) (*JSONL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open events file %s failed: %w", path, err)
+	}
+	return &JSONL{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (j *JSONL) Publish(ctx context.Context, event models.Event) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.enc.Encode(event); err != nil {
+		return fmt.Errorf("write event %d failed: %w", event.Id, err)
+	}
+	return j.file.Sync()
+}
+
+func (j *JSONL) Close() error {
+	return j.file.Close()
+}
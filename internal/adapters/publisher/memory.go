@@ -0,0 +1,33 @@
+package publisher
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"sync"
+)
+
+type Memory struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Publish(ctx context.Context, event models.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *Memory) Events() []models.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]models.Event, len(m.events))
+	copy(events, m.events)
+	return events
+}
@@ -0,0 +1,225 @@
+package statement
+
+import (
+	"balance/internal/domain/models"
+	"encoding/xml"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"
+	isoDateTime      = "2006-01-02T15:04:05Z07:00"
+	isoDate          = "2006-01-02"
+	maxUstrdLength   = 140
+)
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Dt   string `xml:"Dt,omitempty"`
+	DtTm string `xml:"DtTm,omitempty"`
+}
+
+type camtBalance struct {
+	Tp struct {
+		CdOrPrtry struct {
+			Cd string `xml:"Cd"`
+		} `xml:"CdOrPrtry"`
+	} `xml:"Tp"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        camtDate   `xml:"Dt"`
+}
+
+type camtAccount struct {
+	Id struct {
+		Othr struct {
+			Id string `xml:"Id"`
+		} `xml:"Othr"`
+	} `xml:"Id"`
+	Ccy string `xml:"Ccy"`
+}
+
+type camtEntry struct {
+	XMLName   xml.Name   `xml:"Ntry"`
+	NtryRef   string     `xml:"NtryRef"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Sts       struct {
+		Cd string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookgDt     camtDate `xml:"BookgDt"`
+	ValDt       camtDate `xml:"ValDt"`
+	AcctSvcrRef string   `xml:"AcctSvcrRef"`
+	BkTxCd      struct {
+		Prtry struct {
+			Cd   string `xml:"Cd"`
+			Issr string `xml:"Issr"`
+		} `xml:"Prtry"`
+	} `xml:"BkTxCd"`
+	NtryDtls struct {
+		TxDtls struct {
+			Refs struct {
+				AcctSvcrRef string `xml:"AcctSvcrRef"`
+				EndToEndId  string `xml:"EndToEndId"`
+			} `xml:"Refs"`
+			RmtInf *camtRemittance `xml:"RmtInf,omitempty"`
+		} `xml:"TxDtls"`
+	} `xml:"NtryDtls"`
+}
+
+type camtRemittance struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+type Camt053Writer struct {
+	enc       *xml.Encoder
+	statement models.Statement
+	created   time.Time
+}
+
+func NewCamt053(w io.Writer) *Camt053Writer {
+	return &Camt053Writer{enc: xml.NewEncoder(w), created: time.Now().UTC()}
+}
+
+func (c *Camt053Writer) WriteHeader(statement models.Statement) error {
+	c.statement = statement
+	id := fmt.Sprintf("STMT-%d-%d", statement.UserId, c.created.Unix())
+
+	err := c.enc.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)})
+	if err != nil {
+		return fmt.Errorf("write camt.053 header failed: %w", err)
+	}
+	tokens := []xml.Token{
+		xml.StartElement{Name: xml.Name{Local: "Document"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace}}},
+		xml.StartElement{Name: xml.Name{Local: "BkToCstmrStmt"}},
+	}
+	for _, token := range tokens {
+		if err = c.enc.EncodeToken(token); err != nil {
+			return fmt.Errorf("write camt.053 header failed: %w", err)
+		}
+	}
+
+	groupHeader := struct {
+		MsgId   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	}{MsgId: id, CreDtTm: c.created.Format(isoDateTime)}
+	if err = c.enc.EncodeElement(groupHeader, xml.StartElement{Name: xml.Name{Local: "GrpHdr"}}); err != nil {
+		return fmt.Errorf("write camt.053 group header failed: %w", err)
+	}
+
+	if err = c.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "Stmt"}}); err != nil {
+		return fmt.Errorf("write camt.053 statement failed: %w", err)
+	}
+
+	account := camtAccount{Ccy: statement.Currency.Code}
+	account.Id.Othr.Id = strconv.FormatInt(statement.UserId, 10)
+	period := struct {
+		FrDtTm string `xml:"FrDtTm"`
+		ToDtTm string `xml:"ToDtTm"`
+	}{FrDtTm: statement.From.UTC().Format(isoDateTime), ToDtTm: statement.To.UTC().Format(isoDateTime)}
+
+	elements := []struct {
+		name  string
+		value interface{}
+	}{
+		{"Id", id},
+		{"CreDtTm", c.created.Format(isoDateTime)},
+		{"FrToDt", period},
+		{"Acct", account},
+		{"Bal", camtBalanceOf("OPBD", statement.Currency, statement.OpeningBalance, statement.From)},
+		{"Bal", camtBalanceOf("CLBD", statement.Currency, statement.ClosingBalance, statement.To)},
+	}
+	for _, element := range elements {
+		err = c.enc.EncodeElement(element.value, xml.StartElement{Name: xml.Name{Local: element.name}})
+		if err != nil {
+			return fmt.Errorf("write camt.053 statement failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Camt053Writer) WriteEntry(entry models.StatementEntry) error {
+	ref := strconv.FormatInt(entry.Id, 10)
+
+	ntry := camtEntry{
+		NtryRef:     ref,
+		Amt:         camtAmountOf(c.statement.Currency, entry.Amount),
+		CdtDbtInd:   creditDebitIndicator(entry.Amount),
+		BookgDt:     camtDate{DtTm: entry.Time.UTC().Format(isoDateTime)},
+		ValDt:       camtDate{Dt: entry.Time.UTC().Format(isoDate)},
+		AcctSvcrRef: ref,
+	}
+	ntry.Sts.Cd = "BOOK"
+	ntry.BkTxCd.Prtry.Cd = transactionCode(entry.Transaction)
+	ntry.BkTxCd.Prtry.Issr = "balance"
+	ntry.NtryDtls.TxDtls.Refs.AcctSvcrRef = ref
+	ntry.NtryDtls.TxDtls.Refs.EndToEndId = "NOTPROVIDED"
+	if entry.Description != "" {
+		ntry.NtryDtls.TxDtls.RmtInf = &camtRemittance{Ustrd: truncate(entry.Description, maxUstrdLength)}
+	}
+
+	if err := c.enc.Encode(ntry); err != nil {
+		return fmt.Errorf("write camt.053 entry failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Camt053Writer) Close() error {
+	for _, name := range []string{"Stmt", "BkToCstmrStmt", "Document"} {
+		if err := c.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return fmt.Errorf("write camt.053 footer failed: %w", err)
+		}
+	}
+	if err := c.enc.Flush(); err != nil {
+		return fmt.Errorf("flush camt.053 failed: %w", err)
+	}
+	return nil
+}
+
+func camtBalanceOf(code string, currency models.Currency, value decimal.Decimal, at time.Time) camtBalance {
+	balance := camtBalance{
+		Amt:       camtAmountOf(currency, value),
+		CdtDbtInd: creditDebitIndicator(value),
+		Dt:        camtDate{DtTm: at.UTC().Format(isoDateTime)},
+	}
+	balance.Tp.CdOrPrtry.Cd = code
+	return balance
+}
+
+func camtAmountOf(currency models.Currency, value decimal.Decimal) camtAmount {
+	return camtAmount{Ccy: currency.Code, Value: value.Abs().StringFixed(currency.Scale)}
+}
+
+func creditDebitIndicator(value decimal.Decimal) string {
+	if value.IsNegative() {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func transactionCode(transaction models.Transaction) string {
+	switch {
+	case transaction.UserIdFrom == 0:
+		return "INCOME"
+	case transaction.UserIdTo == 0:
+		return "EXPENSE"
+	default:
+		return "TRANSFER"
+	}
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,40 @@
+package statement
+
+import (
+	"balance/internal/domain/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+type CSVWriter struct {
+	w         *csv.Writer
+	statement models.Statement
+}
+
+func NewCSV(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteHeader(statement models.Statement) error {
+	c.statement = statement
+	if err := c.w.Write(columns); err != nil {
+		return fmt.Errorf("write csv header failed: %w", err)
+	}
+	return nil
+}
+
+func (c *CSVWriter) WriteEntry(entry models.StatementEntry) error {
+	if err := c.w.Write(entryRecord(c.statement, entry)); err != nil {
+		return fmt.Errorf("write csv row failed: %w", err)
+	}
+	return nil
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("flush csv failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package statement
+
+import (
+	"balance/internal/domain/models"
+	"strconv"
+	"time"
+)
+
+var columns = []string{
+	"id",
+	"occurred_at",
+	"user_id_from",
+	"user_id_to",
+	"description",
+	"amount",
+	"opening_balance",
+	"closing_balance",
+	"running_balance",
+}
+
+func entryRecord(statement models.Statement, entry models.StatementEntry) []string {
+	scale := statement.Currency.Scale
+	return []string{
+		strconv.FormatInt(entry.Id, 10),
+		entry.Time.UTC().Format(time.RFC3339),
+		strconv.FormatInt(entry.UserIdFrom, 10),
+		strconv.FormatInt(entry.UserIdTo, 10),
+		entry.Description,
+		entry.Amount.StringFixed(scale),
+		statement.OpeningBalance.StringFixed(scale),
+		statement.ClosingBalance.StringFixed(scale),
+		entry.RunningBalance.StringFixed(scale),
+	}
+}
@@ -0,0 +1,117 @@
+package statement
+
+import (
+	"archive/zip"
+	"balance/internal/domain/models"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Statement" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+var numericColumns = map[int]bool{0: true, 2: true, 3: true, 5: true, 6: true, 7: true, 8: true}
+
+type XLSXWriter struct {
+	zw        *zip.Writer
+	sheet     *bufio.Writer
+	statement models.Statement
+}
+
+func NewXLSX(w io.Writer) *XLSXWriter {
+	return &XLSXWriter{zw: zip.NewWriter(w)}
+}
+
+func (x *XLSXWriter) WriteHeader(statement models.Statement) error {
+	x.statement = statement
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := x.zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("create xlsx part %s failed: %w", part.name, err)
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return fmt.Errorf("write xlsx part %s failed: %w", part.name, err)
+		}
+	}
+
+	f, err := x.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("create xlsx sheet failed: %w", err)
+	}
+	x.sheet = bufio.NewWriter(f)
+	if _, err = x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return fmt.Errorf("write xlsx sheet failed: %w", err)
+	}
+
+	return x.writeRow(columns, false)
+}
+
+func (x *XLSXWriter) WriteEntry(entry models.StatementEntry) error {
+	return x.writeRow(entryRecord(x.statement, entry), true)
+}
+
+func (x *XLSXWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return fmt.Errorf("write xlsx sheet failed: %w", err)
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return fmt.Errorf("flush xlsx sheet failed: %w", err)
+	}
+	if err := x.zw.Close(); err != nil {
+		return fmt.Errorf("close xlsx failed: %w", err)
+	}
+	return nil
+}
+
+func (x *XLSXWriter) writeRow(record []string, typed bool) error {
+	x.sheet.WriteString("<row>")
+	for i, value := range record {
+		if typed && numericColumns[i] {
+			x.sheet.WriteString(`<c t="n"><v>`)
+			xml.EscapeText(x.sheet, []byte(value))
+			x.sheet.WriteString("</v></c>")
+			continue
+		}
+		x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(x.sheet, []byte(value))
+		x.sheet.WriteString("</t></is></c>")
+	}
+	if _, err := x.sheet.WriteString("</row>"); err != nil {
+		return fmt.Errorf("write xlsx row failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const tracerName = "balance/internal/adapters/tracing"
+
+type balance struct {
+	next ports.BalancePort
+}
+
+func Balance(next ports.BalancePort) ports.BalancePort {
+	return &balance{next: next}
+}
+
+func start(ctx context.Context, operation, tenant string,
+	attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, attribute.String("balance.tenant", tenant))
+	return otel.Tracer(tracerName).Start(ctx, "balance.Service/"+operation, trace.WithAttributes(attributes...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (b *balance) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	ctx, span := start(ctx, "CreateAccount", tenant, attribute.Int64("balance.user_id", account.Id),
+		attribute.String("balance.account_type", string(account.Type)))
+	account, err := b.next.CreateAccount(ctx, tenant, account)
+	end(span, err)
+	return account, err
+}
+
+func (b *balance) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	ctx, span := start(ctx, "GetAccount", tenant, attribute.Int64("balance.user_id", id))
+	account, err := b.next.GetAccount(ctx, tenant, id)
+	end(span, err)
+	return account, err
+}
+
+func (b *balance) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	ctx, span := start(ctx, "AddIncome", tenant, attribute.Int64("balance.user_id", income.UserId))
+	err := b.next.AddIncome(ctx, tenant, income)
+	end(span, err)
+	return err
+}
+
+func (b *balance) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	ctx, span := start(ctx, "AddExpense", tenant, attribute.Int64("balance.user_id", expense.UserId))
+	err := b.next.AddExpense(ctx, tenant, expense)
+	end(span, err)
+	return err
+}
+
+func (b *balance) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	income models.BalanceWithDesc) error {
+	ctx, span := start(ctx, "AddIncomeTx", tenant, attribute.Int64("balance.user_id", income.UserId))
+	err := b.next.AddIncomeTx(ctx, tx, tenant, income)
+	end(span, err)
+	return err
+}
+
+func (b *balance) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	ctx, span := start(ctx, "AddExpenseTx", tenant, attribute.Int64("balance.user_id", expense.UserId))
+	err := b.next.AddExpenseTx(ctx, tx, tenant, expense)
+	end(span, err)
+	return err
+}
+
+func (b *balance) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	ctx, span := start(ctx, "DoTransfer", tenant,
+		attribute.Int64("balance.user_id_from", transaction.UserIdFrom),
+		attribute.Int64("balance.user_id_to", transaction.UserIdTo))
+	err := b.next.DoTransfer(ctx, tenant, transaction)
+	end(span, err)
+	return err
+}
+
+func (b *balance) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	ctx, span := start(ctx, "GetBalance", tenant, attribute.Int64("balance.user_id", userId))
+	balance, err := b.next.GetBalance(ctx, tenant, userId)
+	end(span, err)
+	return balance, err
+}
+
+func (b *balance) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	w ports.StatementWriter) error {
+	ctx, span := start(ctx, "GetStatement", tenant, attribute.Int64("balance.user_id", userId))
+	err := b.next.GetStatement(ctx, tenant, userId, from, to, w)
+	end(span, err)
+	return err
+}
+
+func (b *balance) GetHistory(ctx context.Context, tenant string, userId int64,
+	from, to time.Time) ([]models.Transaction, error) {
+	ctx, span := start(ctx, "GetHistory", tenant, attribute.Int64("balance.user_id", userId))
+	history, err := b.next.GetHistory(ctx, tenant, userId, from, to)
+	end(span, err)
+	return history, err
+}
+
+func (b *balance) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	ctx, span := start(ctx, "SetFrozen", tenant,
+		attribute.Int64("balance.user_id", userId),
+		attribute.Bool("balance.frozen", frozen))
+	err := b.next.SetFrozen(ctx, tenant, userId, frozen)
+	end(span, err)
+	return err
+}
+
+func (b *balance) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	ctx, span := start(ctx, "Reconcile", tenant)
+	reconciliation, err := b.next.Reconcile(ctx, tenant)
+	end(span, err)
+	return reconciliation, err
+}
@@ -1,68 +1,325 @@
 package application
 
 import (
+	"balance/internal/adapters/grpc"
 	"balance/internal/adapters/http"
+	"balance/internal/adapters/memory"
+	"balance/internal/adapters/metrics"
 	"balance/internal/adapters/postgres"
+	"balance/internal/adapters/publisher"
+	"balance/internal/adapters/tracing"
 	"balance/internal/config"
+	"balance/internal/domain/adjustment"
+	"balance/internal/domain/auth"
 	"balance/internal/domain/balance"
+	"balance/internal/domain/health"
+	"balance/internal/domain/models"
+	"balance/internal/domain/outbox"
+	"balance/internal/domain/tenant"
+	"balance/internal/domain/webhook"
+	"balance/internal/ports"
 	"balance/internal/utils"
 	"context"
+	"crypto/tls"
 	"fmt"
 	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	nethttp "net/http"
 	"time"
 )
 
 type App struct {
-	logger     *zap.Logger
-	httpServer *http.Server
+	logger    *zap.Logger
+	lifecycle *Lifecycle
 }
 
-func Start(ctx context.Context, app *App) {
-	logger, _ := zap.NewProduction()
+// Start starts the components in dependency order and registers each one for
+// shutdown as soon as it is up, so Stop releases whatever was started even
+// when Start fails halfway.
+func Start(ctx context.Context, app *App, args []string) error {
+	appConfig, err := config.NewConfig(args)
+	if err != nil {
+		return err
+	}
+
+	logger, err := utils.NewLogger(appConfig.LogLevel, appConfig.LogFormat)
+	if err != nil {
+		return fmt.Errorf("create logger failed: %w", err)
+	}
 	app.logger = logger
+	app.lifecycle = NewLifecycle(logger.Sugar())
+
+	shutdownTracing, err := tracing.New(ctx, tracing.Options{
+		Exporter:    appConfig.TracingExporter,
+		Endpoint:    appConfig.TracingEndpoint,
+		Insecure:    appConfig.TracingInsecure,
+		ServiceName: "balance",
+	})
+	if err != nil {
+		return fmt.Errorf("tracing init failed: %w", err)
+	}
+	app.lifecycle.OnStop("tracing", appConfig.ShutdownTimeout, shutdownTracing)
+
+	currency, err := models.CurrencyByCode(appConfig.Currency)
+	if err != nil {
+		return fmt.Errorf("currency config failed: %w", err)
+	}
+	tenants, tenantRates, err := newTenants(appConfig, currency)
+	if err != nil {
+		return err
+	}
+	transferRules := appConfig.TransferRules
+	if transferRules == "" {
+		transferRules = models.DefaultTransferRules
+	}
+	rules, err := models.ParseTransferRules(transferRules)
+	if err != nil {
+		return fmt.Errorf("transfer rules config failed: %w", err)
+	}
+
+	appMetrics := metrics.New()
+	healthS := health.New(appConfig.HealthTimeout)
+
+	var storage ports.BalanceStoragePort
+	var adjustmentStorage ports.AdjustmentStoragePort
+	var db *postgres.Database
+	switch appConfig.Storage {
+	case "memory":
+		logger.Sugar().Warn("using in-memory storage, balances are lost on restart")
+		memoryStorage := memory.New()
+		storage, adjustmentStorage = memoryStorage, memoryStorage
+	default:
+		db, err = startPostgres(ctx, app, appConfig, healthS)
+		if err != nil {
+			return err
+		}
+		appMetrics.Register(metrics.NewPoolCollector(db), metrics.NewOutboxCollector(db, time.Second))
+		storage, adjustmentStorage = db, db
+	}
+
+	balanceService := balance.New(appMetrics.Storage(storage), currency, logger.Sugar())
+	balanceService.WithTenants(tenants)
+	balanceService.WithTransferRules(rules)
+	balanceS := tracing.Balance(appMetrics.Balance(balanceService))
+	adjustmentS := adjustment.New(balanceS, adjustmentStorage, appConfig.AdjustmentTtl, logger.Sugar())
+	runWorker(app, "adjustment expiry", appConfig.WorkerShutdownTimeout, func(ctx context.Context) {
+		adjustmentS.Run(ctx, appConfig.AdjustmentExpiryInterval)
+	})
+
+	var webhookS ports.WebhookPort
+	var pool ports.PoolStatsPort
+	var authS ports.AuthPort
+	if db != nil {
+		webhooks := webhook.New(db, &nethttp.Client{Timeout: appConfig.WebhookTimeout}, logger.Sugar(),
+			appConfig.WebhookMaxAttempts, appConfig.WebhookBackoff)
+		webhookS = webhooks
+		pool = db
+		runWorker(app, "webhook deliveries", appConfig.WorkerShutdownTimeout, func(ctx context.Context) {
+			webhooks.Run(ctx, appConfig.WebhookInterval)
+		})
+
+		err = startRelay(app, appConfig, db, appMetrics, webhooks, healthS)
+		if err != nil {
+			return err
+		}
+
+		if appConfig.AuthEnabled {
+			authS = auth.New(db, logger.Sugar())
+		}
+	}
+
+	var tokens ports.TokenVerifierPort
+	if appConfig.JwksFile != "" {
+		tokens, err = auth.NewTokenVerifier(appConfig.JwksFile, appConfig.JwtIssuer, appConfig.JwtAudience)
+		if err != nil {
+			return fmt.Errorf("jwks init failed: %w", err)
+		}
+	}
 
-	appConfig, err := config.NewConfig()
+	httpServer, err := http.New(balanceS, webhookS, authS, tokens, http.Limits{
+		Rate:        appConfig.RateLimit,
+		Burst:       appConfig.RateLimitBurst,
+		RouteRates:  appConfig.RateLimitRoutes,
+		TenantRates: tenantRates,
+		MaxInFlight: appConfig.MaxInFlight,
+		MaxPoolWait: appConfig.MaxPoolWait,
+		Pool:        pool,
+	}, logger.Sugar())
 	if err != nil {
-		logger.Sugar().Fatalf("create config failed: %v", err)
+		return fmt.Errorf("http server init failed: %w", err)
+	}
+	httpServer.WithMetrics(appMetrics.Middleware, appMetrics.Handler())
+	httpServer.WithHealth(healthS)
+	httpServer.WithAdjustments(adjustmentS)
+	httpServer.WithTenants(tenants)
+	httpServer.WithTimeouts(appConfig.HttpReadTimeout, appConfig.HttpWriteTimeout, appConfig.RequestTimeout)
+
+	var tlsConfig *tls.Config
+	if appConfig.TlsCertFile != "" {
+		clientAuth := tls.RequireAndVerifyClientCert
+		if appConfig.TlsClientAuth == "verify_if_given" {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+		certs, err := utils.NewCertReloader(appConfig.TlsCertFile, appConfig.TlsKeyFile,
+			appConfig.TlsClientCaFile, clientAuth)
+		if err != nil {
+			return fmt.Errorf("tls init failed: %w", err)
+		}
+		tlsConfig = certs.TLSConfig()
 	}
 
-	pgconn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable",
-		appConfig.PostgresUser, appConfig.PostgresPassword, appConfig.PostgresHost,
-		appConfig.PostgresPort, appConfig.PostgresDb)
+	app.lifecycle.Go("http server", func() error {
+		return httpServer.Start(appConfig.HttpPort, tlsConfig)
+	})
+	app.lifecycle.OnStop("http server", appConfig.ShutdownTimeout, httpServer.Stop)
+
+	grpcServer := grpc.New(balanceS, authS, tokens, logger.Sugar())
+	grpcServer.WithTenants(tenants)
+	app.lifecycle.Go("grpc server", func() error {
+		return grpcServer.Start(appConfig.GrpcPort)
+	})
+	app.lifecycle.OnStop("grpc server", appConfig.ShutdownTimeout, grpcServer.Stop)
 
-	db, err := postgres.New(ctx, pgconn)
+	app.logger.Sugar().Info("application has started")
+	return nil
+}
+
+// newTenants builds the tenant registry and the per-tenant rate limits from
+// the config, tenant settings that are not set fall back to the global ones.
+func newTenants(appConfig *config.Config, currency models.Currency) (*tenant.Registry, map[string]float64, error) {
+	settings := map[string]models.TenantSettings{}
+	rates := map[string]float64{}
+	for id, tenantConfig := range appConfig.Tenants {
+		tenantSettings := models.TenantSettings{Currency: currency}
+		if tenantConfig.Currency != "" {
+			tenantCurrency, err := models.CurrencyByCode(tenantConfig.Currency)
+			if err != nil {
+				return nil, nil, fmt.Errorf("tenant %s currency config failed: %w", id, err)
+			}
+			tenantSettings.Currency = tenantCurrency
+		}
+		if tenantConfig.MaxOperation != "" {
+			tenantSettings.MaxOperation = decimal.RequireFromString(tenantConfig.MaxOperation)
+		}
+		if tenantConfig.RateLimit > 0 {
+			rates[id] = tenantConfig.RateLimit
+		}
+		settings[id] = tenantSettings
+	}
+	return tenant.NewRegistry(models.TenantSettings{Currency: currency}, settings), rates, nil
+}
+
+func startPostgres(ctx context.Context, app *App, appConfig *config.Config,
+	healthS *health.Service) (*postgres.Database, error) {
+	pgconn := postgres.Options{
+		User:        appConfig.PostgresUser,
+		Password:    appConfig.PostgresPassword,
+		Host:        appConfig.PostgresHost,
+		Port:        appConfig.PostgresPort,
+		Database:    appConfig.PostgresDb,
+		SslMode:     appConfig.PostgresSslMode,
+		SslRootCert: appConfig.PostgresSslRootCert,
+		SslCert:     appConfig.PostgresSslCert,
+		SslKey:      appConfig.PostgresSslKey,
+	}.ConnString()
+
+	db, err := postgres.New(ctx, pgconn, postgres.PoolOptions{
+		MaxConns:         appConfig.PostgresMaxConns,
+		MinConns:         appConfig.PostgresMinConns,
+		MaxConnLifetime:  appConfig.PostgresMaxConnLifetime,
+		MaxConnIdleTime:  appConfig.PostgresMaxConnIdleTime,
+		StatementTimeout: appConfig.PostgresStatementTimeout,
+	})
 	if err != nil {
-		logger.Sugar().Fatalf("db init failed: %v", err)
+		return nil, fmt.Errorf("db init failed: %w", err)
 	}
+	app.lifecycle.OnStop("postgres pool", appConfig.ShutdownTimeout, func(ctx context.Context) error {
+		db.Close()
+		return nil
+	})
 
 	err = utils.ApplyMigrations(pgconn, "db/changelog/")
 	if err != nil {
-		logger.Sugar().Fatalf("migrations failed: %v", err)
+		return nil, fmt.Errorf("migrations failed: %w", err)
 	}
 
-	balanceS := balance.New(db, logger.Sugar())
+	migrationVersion, err := utils.LatestMigrationVersion("db/changelog/")
+	if err != nil {
+		return nil, fmt.Errorf("migrations version failed: %w", err)
+	}
 
-	app.httpServer = http.New(balanceS, logger.Sugar())
+	healthS.Register("postgres", health.DatabaseCheck(db))
+	healthS.Register("migrations", health.MigrationCheck(db, migrationVersion))
+	return db, nil
+}
 
-	go func() {
-		err := app.httpServer.Start(appConfig.HttpPort)
+func startRelay(app *App, appConfig *config.Config, db *postgres.Database, appMetrics *metrics.Metrics,
+	webhooks *webhook.Service, healthS *health.Service) error {
+	publishers := []ports.EventPublisher{appMetrics.Publisher("webhooks", webhooks)}
+	if appConfig.OutboxFile != "" {
+		eventsFile, err := publisher.NewJSONL(appConfig.OutboxFile)
 		if err != nil {
-			logger.Sugar().Fatalf("http server failed: %v", err)
+			return fmt.Errorf("events publisher init failed: %w", err)
 		}
-	}()
+		app.lifecycle.OnStop("events file", appConfig.ShutdownTimeout, func(ctx context.Context) error {
+			return eventsFile.Close()
+		})
+		publishers = append(publishers, appMetrics.Publisher("jsonl", eventsFile))
+	}
+	relay := outbox.New(db, publisher.NewFanout(publishers...), app.logger.Sugar(),
+		appConfig.OutboxInterval, appConfig.OutboxBatchSize)
+	relay.WithStopTimeout(appConfig.WorkerShutdownTimeout)
 
-	app.logger.Sugar().Info("application has started")
+	runWorker(app, "outbox relay", appConfig.WorkerShutdownTimeout, relay.Run)
+
+	healthS.Register("outbox_relay", relay.Check)
+	return nil
 }
 
-func Stop(app *App) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// runWorker runs a background loop under its own context, which is cancelled
+// on stop, and waits up to timeout for the loop to return.
+func runWorker(app *App, name string, timeout time.Duration, run func(ctx context.Context)) {
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	app.lifecycle.Go(name, func() error {
+		defer close(workerDone)
+		run(workerCtx)
+		return nil
+	})
+	app.lifecycle.OnStop(name, timeout, func(ctx context.Context) error {
+		stopWorker()
+		select {
+		case <-workerDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Wait blocks until ctx is cancelled or a running component fails.
+func Wait(ctx context.Context, app *App) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-app.lifecycle.Err():
+		app.logger.Sugar().Errorf("application failed: %v", err)
+		return err
+	}
+}
+
+func Stop(app *App) error {
+	if app.lifecycle == nil {
+		return nil
+	}
 
-	err := app.httpServer.Stop(ctx)
+	err := app.lifecycle.Stop()
 	if err != nil {
-		app.logger.Sugar().Errorf("stop http server failed: %v", err)
+		app.logger.Sugar().Errorf("app stop failed: %v", err)
+		return err
 	}
 
 	app.logger.Sugar().Info("app has stopped")
+	return nil
 }
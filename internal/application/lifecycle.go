@@ -0,0 +1,86 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+type stopHook struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// Lifecycle tracks started components so they can be stopped in reverse
+// order, and collects failures of components running in the background.
+type Lifecycle struct {
+	logger *zap.SugaredLogger
+
+	mu    sync.Mutex
+	hooks []stopHook
+	errs  chan error
+}
+
+func NewLifecycle(logger *zap.SugaredLogger) *Lifecycle {
+	return &Lifecycle{
+		logger: logger,
+		errs:   make(chan error, 1),
+	}
+}
+
+// OnStop registers stop to run on shutdown with its own timeout. Hooks run in
+// reverse registration order, so a component is stopped before the
+// dependencies it was started after.
+func (l *Lifecycle) OnStop(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, stopHook{name: name, timeout: timeout, stop: stop})
+}
+
+// Go runs a long-running component. A non-nil error returned by run is
+// reported on Err, the first one wins.
+func (l *Lifecycle) Go(name string, run func() error) {
+	go func() {
+		if err := run(); err != nil {
+			select {
+			case l.errs <- fmt.Errorf("%s failed: %w", name, err):
+			default:
+				l.logger.Errorf("%s failed: %v", name, err)
+			}
+		}
+	}()
+}
+
+func (l *Lifecycle) Err() <-chan error {
+	return l.errs
+}
+
+func (l *Lifecycle) Stop() error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	var failed []string
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		ctx, cancel := context.WithTimeout(context.Background(), hook.timeout)
+		err := hook.stop(ctx)
+		cancel()
+		if err != nil {
+			l.logger.Errorf("stop %s failed: %v", hook.name, err)
+			failed = append(failed, hook.name)
+			continue
+		}
+		l.logger.Infof("%s has stopped", hook.name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("stop failed for: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
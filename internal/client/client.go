@@ -0,0 +1,277 @@
+// Package client talks to the balance service HTTP API.
+package client
+
+import (
+	"balance/internal/domain/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	// BaseURL is the service address, e.g. http://localhost:3000.
+	BaseURL string
+	ApiKey  string
+	// Token is an end-user JWT sent as a bearer token.
+	Token string
+	// Tenant is sent in the X-Tenant-Id header, empty leaves the tenant to
+	// the credentials or the default tenant.
+	Tenant     string
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	baseURL string
+	apiKey  string
+	token   string
+	tenant  string
+	http    *http.Client
+}
+
+// Error is an RFC 7807 problem returned by the service.
+type Error struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s (%d %s)", e.Detail, e.Status, e.Code)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Title, e.Status, e.Code)
+}
+
+type HistoryEntry struct {
+	Id          int64           `json:"id"`
+	UserIdFrom  int64           `json:"user_id_from"`
+	UserIdTo    int64           `json:"user_id_to"`
+	Value       decimal.Decimal `json:"value"`
+	Description string          `json:"description"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+func New(options Options) *Client {
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(options.BaseURL, "/"),
+		apiKey:  options.ApiKey,
+		token:   options.Token,
+		tenant:  options.Tenant,
+		http:    httpClient,
+	}
+}
+
+func (c *Client) GetBalance(ctx context.Context, userId int64) (models.Balance, error) {
+	var balance models.Balance
+	err := c.do(ctx, http.MethodGet, "/balance/v1/balance", userQuery(userId), nil, &balance)
+	return balance, err
+}
+
+// GetHistory lists balance changes in [from, to). A zero from or to leaves the
+// bound to the service defaults: the beginning of history and now.
+func (c *Client) GetHistory(ctx context.Context, userId int64, from, to time.Time) ([]HistoryEntry, error) {
+	var history []HistoryEntry
+	err := c.do(ctx, http.MethodGet, "/balance/v1/history", periodQuery(userId, from, to), nil, &history)
+	return history, err
+}
+
+// Statement streams the statement file in the given format (csv, xlsx or
+// camt053) to w.
+func (c *Client) Statement(ctx context.Context, userId int64, from, to time.Time, format string, w io.Writer) error {
+	query := periodQuery(userId, from, to)
+	if format != "" {
+		query.Set("format", format)
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/balance/v1/statements", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Propose creates a pending credit (positive value) or debit (negative value)
+// that another operator has to approve before it is posted.
+func (c *Client) Propose(ctx context.Context, userId int64, value decimal.Decimal,
+	reason, attachmentRef string) (models.Adjustment, error) {
+	request := struct {
+		UserId        int64  `json:"user_id"`
+		Value         string `json:"value"`
+		Reason        string `json:"reason"`
+		AttachmentRef string `json:"attachment_ref,omitempty"`
+	}{UserId: userId, Value: value.String(), Reason: reason, AttachmentRef: attachmentRef}
+
+	var adjustment models.Adjustment
+	err := c.do(ctx, http.MethodPost, "/admin/v1/adjustments", nil, request, &adjustment)
+	return adjustment, err
+}
+
+func (c *Client) Approve(ctx context.Context, id int64) (models.Adjustment, error) {
+	var adjustment models.Adjustment
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/v1/adjustments/%d/approve", id), nil, nil, &adjustment)
+	return adjustment, err
+}
+
+func (c *Client) Reject(ctx context.Context, id int64, comment string) (models.Adjustment, error) {
+	request := struct {
+		Comment string `json:"comment,omitempty"`
+	}{Comment: comment}
+
+	var adjustment models.Adjustment
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/v1/adjustments/%d/reject", id), nil, request,
+		&adjustment)
+	return adjustment, err
+}
+
+// GetAdjustment returns the adjustment with its audit trail.
+func (c *Client) GetAdjustment(ctx context.Context, id int64) (models.Adjustment, error) {
+	var adjustment models.Adjustment
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/v1/adjustments/%d", id), nil, nil, &adjustment)
+	return adjustment, err
+}
+
+// ListAdjustments filters by user and status, zero values match any.
+func (c *Client) ListAdjustments(ctx context.Context, userId int64, status string) ([]models.Adjustment, error) {
+	query := url.Values{}
+	if userId != 0 {
+		query = userQuery(userId)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var adjustments []models.Adjustment
+	err := c.do(ctx, http.MethodGet, "/admin/v1/adjustments", query, nil, &adjustments)
+	return adjustments, err
+}
+
+func (c *Client) CreateAccount(ctx context.Context, id int64, accountType models.AccountType,
+	ownerRef string) (models.Account, error) {
+	request := struct {
+		Id       int64              `json:"id"`
+		Type     models.AccountType `json:"type"`
+		OwnerRef string             `json:"owner_ref,omitempty"`
+	}{Id: id, Type: accountType, OwnerRef: ownerRef}
+
+	var account models.Account
+	err := c.do(ctx, http.MethodPost, "/admin/v1/accounts", nil, request, &account)
+	return account, err
+}
+
+func (c *Client) GetAccount(ctx context.Context, id int64) (models.Account, error) {
+	var account models.Account
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/v1/accounts/%d", id), nil, nil, &account)
+	return account, err
+}
+
+func (c *Client) Freeze(ctx context.Context, userId int64) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/v1/accounts/%d/freeze", userId), nil, nil, nil)
+}
+
+func (c *Client) Unfreeze(ctx context.Context, userId int64) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/v1/accounts/%d/unfreeze", userId), nil, nil, nil)
+}
+
+func (c *Client) Reconcile(ctx context.Context) (models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+	err := c.do(ctx, http.MethodGet, "/admin/v1/reconciliation", nil, nil, &reconciliation)
+	return reconciliation, err
+}
+
+func userQuery(userId int64) url.Values {
+	return url.Values{"user_id": {strconv.FormatInt(userId, 10)}}
+}
+
+func periodQuery(userId int64, from, to time.Time) url.Values {
+	query := userQuery(userId)
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	return query
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode %s %s response failed: %w", method, path, err)
+	}
+	return nil
+}
+
+// send performs the request and turns any non-2xx response into an *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-Id", c.tenant)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	problem := &Error{Status: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(raw, problem) != nil || problem.Code == "" {
+		problem.Status = resp.StatusCode
+		problem.Title = http.StatusText(resp.StatusCode)
+		problem.Detail = strings.TrimSpace(string(raw))
+	}
+	return nil, problem
+}
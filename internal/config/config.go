@@ -1,25 +1,119 @@
 package config
 
 import (
-	"github.com/kelseyhightower/envconfig"
+	"errors"
+	"strings"
+	"time"
 )
 
+// Config is loaded in layers: defaults from the default tags, then the YAML
+// file, then environment variables, then command line flags. The YAML key
+// names each setting, the environment variable is the upper-cased key and the
+// flag is the key with dashes. Settings tagged secret can also be read from
+// the file named by <ENV>_FILE.
 type Config struct {
-	HttpPort         string `split_words:"true"`
-	PostgresUser     string `split_words:"true"`
-	PostgresPassword string `split_words:"true"`
-	PostgresHost     string `split_words:"true"`
-	PostgresPort     string `split_words:"true"`
-	PostgresDb       string `split_words:"true"`
+	HttpPort         string        `yaml:"http_port"`
+	GrpcPort         string        `yaml:"grpc_port" default:"3001"`
+	HttpReadTimeout  time.Duration `yaml:"http_read_timeout" default:"10s"`
+	HttpWriteTimeout time.Duration `yaml:"http_write_timeout" default:"60s"`
+	RequestTimeout   time.Duration `yaml:"request_timeout" default:"30s"`
+
+	Storage string `yaml:"storage" default:"postgres"`
+
+	PostgresUser        string `yaml:"postgres_user"`
+	PostgresPassword    string `yaml:"postgres_password" secret:"true"`
+	PostgresHost        string `yaml:"postgres_host"`
+	PostgresPort        string `yaml:"postgres_port" default:"5432"`
+	PostgresDb          string `yaml:"postgres_db"`
+	PostgresSslMode     string `yaml:"postgres_ssl_mode" default:"disable"`
+	PostgresSslRootCert string `yaml:"postgres_ssl_root_cert"`
+	PostgresSslCert     string `yaml:"postgres_ssl_cert"`
+	PostgresSslKey      string `yaml:"postgres_ssl_key"`
+
+	PostgresMaxConns         int32         `yaml:"postgres_max_conns" default:"10"`
+	PostgresMinConns         int32         `yaml:"postgres_min_conns" default:"0"`
+	PostgresMaxConnLifetime  time.Duration `yaml:"postgres_max_conn_lifetime" default:"1h"`
+	PostgresMaxConnIdleTime  time.Duration `yaml:"postgres_max_conn_idle_time" default:"30m"`
+	PostgresStatementTimeout time.Duration `yaml:"postgres_statement_timeout" default:"30s"`
+
+	Currency    string `yaml:"currency" default:"RUB"`
+	AuthEnabled bool   `yaml:"auth_enabled" default:"false"`
+	JwksFile    string `yaml:"jwks_file"`
+	JwtIssuer   string `yaml:"jwt_issuer"`
+	JwtAudience string `yaml:"jwt_audience"`
+
+	TlsCertFile     string `yaml:"tls_cert_file"`
+	TlsKeyFile      string `yaml:"tls_key_file"`
+	TlsClientCaFile string `yaml:"tls_client_ca_file"`
+	TlsClientAuth   string `yaml:"tls_client_auth" default:"require"`
+
+	RateLimit       float64            `yaml:"rate_limit"`
+	RateLimitBurst  int                `yaml:"rate_limit_burst"`
+	RateLimitRoutes map[string]float64 `yaml:"rate_limit_routes"`
+	MaxInFlight     int                `yaml:"max_in_flight"`
+	MaxPoolWait     time.Duration      `yaml:"max_pool_wait"`
+
+	LogLevel  string `yaml:"log_level" default:"info"`
+	LogFormat string `yaml:"log_format" default:"json"`
+
+	TracingExporter string `yaml:"tracing_exporter" default:"none"`
+	TracingEndpoint string `yaml:"tracing_endpoint"`
+	TracingInsecure bool   `yaml:"tracing_insecure"`
+
+	HealthTimeout         time.Duration `yaml:"health_timeout" default:"2s"`
+	ShutdownTimeout       time.Duration `yaml:"shutdown_timeout" default:"15s"`
+	WorkerShutdownTimeout time.Duration `yaml:"worker_shutdown_timeout" default:"10s"`
+
+	OutboxFile      string        `yaml:"outbox_file"`
+	OutboxInterval  time.Duration `yaml:"outbox_interval" default:"1s"`
+	OutboxBatchSize int           `yaml:"outbox_batch_size" default:"100"`
+
+	WebhookMaxAttempts int           `yaml:"webhook_max_attempts" default:"5"`
+	WebhookBackoff     time.Duration `yaml:"webhook_backoff" default:"500ms"`
+	WebhookTimeout     time.Duration `yaml:"webhook_timeout" default:"5s"`
+	WebhookInterval    time.Duration `yaml:"webhook_interval" default:"1s"`
+
+	AdjustmentTtl            time.Duration `yaml:"adjustment_ttl" default:"72h"`
+	AdjustmentExpiryInterval time.Duration `yaml:"adjustment_expiry_interval" default:"1m"`
+
+	TransferRules string `yaml:"transfer_rules"`
+
+	Tenants map[string]TenantConfig `yaml:"tenants"`
 }
 
-func NewConfig() (*Config, error) {
+// TenantConfig overrides settings for one tenant, unset values fall back to
+// the global currency and rate_limit. Tenants are configured in the YAML file
+// only.
+type TenantConfig struct {
+	Currency     string  `yaml:"currency"`
+	MaxOperation string  `yaml:"max_operation"`
+	RateLimit    float64 `yaml:"rate_limit"`
+}
+
+// NewConfig loads the configuration from the YAML file given by the -config
+// flag or CONFIG_FILE, the environment and args, and validates it. All invalid
+// settings are reported in a single error.
+func NewConfig(args []string) (*Config, error) {
 	var s Config
 
-	err := envconfig.Process("", &s)
-	if err != nil {
+	var errs Errors
+	err := load(&s, args)
+	if err != nil && !errors.As(err, &errs) {
 		return nil, err
 	}
 
+	// a setting that failed to parse is reported once, not again as invalid
+	var invalid Errors
+	if errors.As(s.Validate(), &invalid) {
+		for _, e := range invalid {
+			if !errs.has(strings.SplitN(e, ": ", 2)[0]) {
+				errs = append(errs, e)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return &s, nil
 }
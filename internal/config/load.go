@@ -0,0 +1,214 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type field struct {
+	key    string
+	value  reflect.Value
+	def    string
+	secret bool
+}
+
+func (f field) env() string {
+	return strings.ToUpper(f.key)
+}
+
+func (f field) flag() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+func fields(s *Config) []field {
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		result = append(result, field{
+			key:    tag.Get("yaml"),
+			value:  v.Field(i),
+			def:    tag.Get("default"),
+			secret: tag.Get("secret") == "true",
+		})
+	}
+	return result
+}
+
+type flagValue struct {
+	isBool bool
+	set    func(string)
+}
+
+func (f flagValue) String() string {
+	return ""
+}
+
+func (f flagValue) Set(raw string) error {
+	f.set(raw)
+	return nil
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+type flagSetting struct {
+	field field
+	raw   string
+}
+
+func load(s *Config, args []string) error {
+	var errs Errors
+	all := fields(s)
+
+	for _, f := range all {
+		if f.def == "" {
+			continue
+		}
+		if err := setField(f.value, f.def); err != nil {
+			errs.add(f.key, "invalid default %q: %v", f.def, err)
+		}
+	}
+
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file")
+	var flags []flagSetting
+	for _, f := range all {
+		f := f
+		fs.Var(flagValue{
+			isBool: f.value.Kind() == reflect.Bool,
+			set: func(raw string) {
+				flags = append(flags, flagSetting{field: f, raw: raw})
+			},
+		}, f.flag(), fmt.Sprintf("overrides %s", f.env()))
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(s, *configFile); err != nil {
+			errs.add("config", "%v", err)
+		}
+	}
+
+	for _, f := range all {
+		raw, ok := os.LookupEnv(f.env())
+		if f.secret {
+			if path, isFile := os.LookupEnv(f.env() + "_FILE"); isFile {
+				if ok {
+					errs.add(f.key, "both %s and %s_FILE are set", f.env(), f.env())
+					continue
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					errs.add(f.key, "read %s_FILE failed: %v", f.env(), err)
+					continue
+				}
+				raw, ok = strings.TrimRight(string(content), "\r\n"), true
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(f.value, raw); err != nil {
+			errs.add(f.key, "invalid %s value: %v", f.env(), err)
+		}
+	}
+
+	for _, setting := range flags {
+		if err := setField(setting.field.value, setting.raw); err != nil {
+			errs.add(setting.field.key, "invalid -%s value: %v", setting.field.flag(), err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func loadFile(s *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(s)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setField(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Float64 {
+			return errors.New("can only be set in the config file")
+		}
+		m := map[string]float64{}
+		for _, pair := range strings.Split(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, ":", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("expected key:value pairs, got %q", pair)
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				return err
+			}
+			m[strings.TrimSpace(kv[0])] = f
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
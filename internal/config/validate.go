@@ -0,0 +1,230 @@
+package config
+
+import (
+	"balance/internal/domain/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tenantIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Errors lists every invalid setting, each prefixed with its YAML key.
+type Errors []string
+
+func (e *Errors) add(key string, format string, args ...interface{}) {
+	*e = append(*e, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (e Errors) has(key string) bool {
+	for _, err := range e {
+		if strings.HasPrefix(err, key+": ") {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Errors) port(key, value string) {
+	if value == "" {
+		e.add(key, "is required")
+		return
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		e.add(key, "%q is not a valid port", value)
+	}
+}
+
+func (e *Errors) required(key, value string) {
+	if value == "" {
+		e.add(key, "is required")
+	}
+}
+
+func (e *Errors) positive(key string, value time.Duration) {
+	if value <= 0 {
+		e.add(key, "must be positive, got %s", value)
+	}
+}
+
+func (e *Errors) nonNegative(key string, value time.Duration) {
+	if value < 0 {
+		e.add(key, "must not be negative, got %s", value)
+	}
+}
+
+// transferRules checks the comma separated from:to pairs of account types
+// that models.ParseTransferRules parses at startup.
+func (e *Errors) transferRules(value string) {
+	pairs := 0
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		types := strings.SplitN(pair, ":", 2)
+		if len(types) != 2 {
+			e.add("transfer_rules", "expected from:to pairs, got %q", pair)
+			continue
+		}
+		for _, accountType := range types {
+			if !oneOf(strings.TrimSpace(accountType), "user", "merchant", "system") {
+				e.add("transfer_rules", "unknown account type %q in %q", strings.TrimSpace(accountType), pair)
+			}
+		}
+		pairs++
+	}
+	if pairs == 0 {
+		e.add("transfer_rules", "must list at least one from:to pair")
+	}
+}
+
+func (s *Config) Validate() error {
+	var errs Errors
+
+	errs.port("http_port", s.HttpPort)
+	errs.port("grpc_port", s.GrpcPort)
+	errs.nonNegative("http_read_timeout", s.HttpReadTimeout)
+	errs.nonNegative("http_write_timeout", s.HttpWriteTimeout)
+	errs.nonNegative("request_timeout", s.RequestTimeout)
+	if s.HttpWriteTimeout > 0 && s.RequestTimeout > 0 && s.HttpWriteTimeout <= s.RequestTimeout {
+		errs.add("http_write_timeout", "must be greater than request_timeout %s", s.RequestTimeout)
+	}
+
+	switch s.Storage {
+	case "postgres":
+		errs.required("postgres_user", s.PostgresUser)
+		errs.required("postgres_host", s.PostgresHost)
+		errs.port("postgres_port", s.PostgresPort)
+		errs.required("postgres_db", s.PostgresDb)
+	case "memory":
+		if s.AuthEnabled {
+			errs.add("auth_enabled", "requires the postgres storage")
+		}
+	default:
+		errs.add("storage", "must be postgres or memory, got %q", s.Storage)
+	}
+	if !oneOf(s.PostgresSslMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full") {
+		errs.add("postgres_ssl_mode", "unknown mode %q", s.PostgresSslMode)
+	}
+	if s.PostgresMaxConns < 1 {
+		errs.add("postgres_max_conns", "must be at least 1, got %d", s.PostgresMaxConns)
+	}
+	if s.PostgresMinConns < 0 || s.PostgresMinConns > s.PostgresMaxConns {
+		errs.add("postgres_min_conns", "must be between 0 and postgres_max_conns, got %d", s.PostgresMinConns)
+	}
+	errs.positive("postgres_max_conn_lifetime", s.PostgresMaxConnLifetime)
+	errs.positive("postgres_max_conn_idle_time", s.PostgresMaxConnIdleTime)
+	errs.nonNegative("postgres_statement_timeout", s.PostgresStatementTimeout)
+
+	errs.required("currency", s.Currency)
+	if s.Currency != "" {
+		if _, err := models.CurrencyByCode(s.Currency); err != nil {
+			errs.add("currency", "%v", err)
+		}
+	}
+
+	if (s.TlsCertFile == "") != (s.TlsKeyFile == "") {
+		errs.add("tls_cert_file", "tls_cert_file and tls_key_file must be set together")
+	}
+	if s.TlsClientCaFile != "" && s.TlsCertFile == "" {
+		errs.add("tls_client_ca_file", "requires tls_cert_file")
+	}
+	if !oneOf(s.TlsClientAuth, "require", "verify_if_given") {
+		errs.add("tls_client_auth", "must be require or verify_if_given, got %q", s.TlsClientAuth)
+	}
+
+	if s.RateLimit < 0 {
+		errs.add("rate_limit", "must not be negative, got %v", s.RateLimit)
+	}
+	if s.RateLimitBurst < 0 {
+		errs.add("rate_limit_burst", "must not be negative, got %d", s.RateLimitBurst)
+	}
+	for route, rate := range s.RateLimitRoutes {
+		if rate < 0 {
+			errs.add("rate_limit_routes", "rate for %s must not be negative, got %v", route, rate)
+		}
+	}
+	if s.MaxInFlight < 0 {
+		errs.add("max_in_flight", "must not be negative, got %d", s.MaxInFlight)
+	}
+	errs.nonNegative("max_pool_wait", s.MaxPoolWait)
+
+	if !oneOf(s.LogLevel, "debug", "info", "warn", "error") {
+		errs.add("log_level", "must be debug, info, warn or error, got %q", s.LogLevel)
+	}
+	if !oneOf(s.LogFormat, "json", "console") {
+		errs.add("log_format", "must be json or console, got %q", s.LogFormat)
+	}
+
+	if !oneOf(s.TracingExporter, "none", "stdout", "otlp") {
+		errs.add("tracing_exporter", "must be none, stdout or otlp, got %q", s.TracingExporter)
+	}
+	if s.TracingExporter == "otlp" && s.TracingEndpoint == "" {
+		errs.add("tracing_endpoint", "is required for the otlp exporter")
+	}
+
+	errs.positive("health_timeout", s.HealthTimeout)
+	errs.positive("shutdown_timeout", s.ShutdownTimeout)
+	errs.positive("worker_shutdown_timeout", s.WorkerShutdownTimeout)
+
+	errs.positive("outbox_interval", s.OutboxInterval)
+	if s.OutboxBatchSize < 1 {
+		errs.add("outbox_batch_size", "must be at least 1, got %d", s.OutboxBatchSize)
+	}
+
+	if s.WebhookMaxAttempts < 1 {
+		errs.add("webhook_max_attempts", "must be at least 1, got %d", s.WebhookMaxAttempts)
+	}
+	errs.nonNegative("webhook_backoff", s.WebhookBackoff)
+	errs.positive("webhook_timeout", s.WebhookTimeout)
+	errs.positive("webhook_interval", s.WebhookInterval)
+
+	errs.positive("adjustment_ttl", s.AdjustmentTtl)
+	errs.positive("adjustment_expiry_interval", s.AdjustmentExpiryInterval)
+
+	if s.TransferRules != "" {
+		errs.transferRules(s.TransferRules)
+	}
+
+	for id, tenant := range s.Tenants {
+		if !tenantIdPattern.MatchString(id) {
+			errs.add("tenants", "tenant id %q must be lowercase letters, digits, - and _", id)
+		}
+		if tenant.Currency != "" {
+			if _, err := models.CurrencyByCode(tenant.Currency); err != nil {
+				errs.add("tenants", "currency of %s: %v", id, err)
+			}
+		}
+		if tenant.MaxOperation != "" {
+			max, err := decimal.NewFromString(tenant.MaxOperation)
+			if err != nil || !max.IsPositive() {
+				errs.add("tenants", "max_operation of %s must be a positive decimal, got %q", id, tenant.MaxOperation)
+			}
+		}
+		if tenant.RateLimit < 0 {
+			errs.add("tenants", "rate_limit of %s must not be negative, got %v", id, tenant.RateLimit)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
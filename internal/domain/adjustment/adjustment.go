@@ -0,0 +1,297 @@
+package adjustment
+
+import (
+	"balance/internal/domain/auth"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"balance/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DescriptionPrefix      = "adjustment: "
+	MaxReasonLength        = 200
+	MaxAttachmentRefLength = 500
+	MaxCommentLength       = 500
+)
+
+type Service struct {
+	balance ports.BalancePort
+	db      ports.AdjustmentStoragePort
+	ttl     time.Duration
+	logger  *zap.SugaredLogger
+}
+
+// New creates the maker-checker service. Approved adjustments are posted
+// through balance, which must use the same storage as db. Proposals that are
+// not decided within ttl expire.
+func New(balance ports.BalancePort, db ports.AdjustmentStoragePort, ttl time.Duration,
+	logger *zap.SugaredLogger) *Service {
+	return &Service{
+		balance: balance,
+		db:      db,
+		ttl:     ttl,
+		logger:  logger,
+	}
+}
+
+// operator returns the name of the authenticated client. Maker-checker is
+// meaningless without knowing who proposes and who approves, so anonymous
+// requests are refused even when authentication is disabled.
+func operator(ctx context.Context) (string, error) {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return "", e.ForbiddenError
+	}
+	client, ok := auth.ClientFromContext(ctx)
+	if !ok || client.Name == "" {
+		return "", fmt.Errorf("adjustments require an authenticated operator: %w", e.UnauthenticatedError)
+	}
+	return client.Name, nil
+}
+
+func (s *Service) Propose(ctx context.Context, tenant string, adjustment models.Adjustment) (models.Adjustment, error) {
+	proposer, err := operator(ctx)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	adjustment.Reason = strings.TrimSpace(adjustment.Reason)
+	adjustment.AttachmentRef = strings.TrimSpace(adjustment.AttachmentRef)
+	var fields []e.FieldError
+	if adjustment.UserId <= 0 {
+		fields = append(fields, e.FieldError{Field: "user_id", Message: "must be positive"})
+	}
+	if adjustment.Reason == "" {
+		fields = append(fields, e.FieldError{Field: "reason", Message: "is required"})
+	} else if utf8.RuneCountInString(adjustment.Reason) > MaxReasonLength {
+		fields = append(fields, e.FieldError{Field: "reason",
+			Message: fmt.Sprintf("must be at most %d characters", MaxReasonLength)})
+	}
+	if utf8.RuneCountInString(adjustment.AttachmentRef) > MaxAttachmentRefLength {
+		fields = append(fields, e.FieldError{Field: "attachment_ref",
+			Message: fmt.Sprintf("must be at most %d characters", MaxAttachmentRefLength)})
+	}
+	if adjustment.Value.IsZero() {
+		fields = append(fields, e.FieldError{Field: "value", Message: "must not be zero"})
+	}
+	if len(fields) > 0 {
+		return models.Adjustment{}, &e.ValidationError{Fields: fields}
+	}
+
+	adjustment.Status = models.AdjustmentPending
+	adjustment.ProposedBy = proposer
+	adjustment.DecidedBy = ""
+	adjustment.DecidedAt = nil
+	adjustment.CreatedAt = time.Now()
+	adjustment.ExpiresAt = adjustment.CreatedAt.Add(s.ttl)
+
+	adjustment, err = s.db.AddAdjustment(ctx, tenant, adjustment)
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("add adjustment fail", "error", err, "tenant", tenant,
+			"user_id", adjustment.UserId)
+		return models.Adjustment{}, e.DatabaseError
+	}
+
+	utils.Logger(ctx, s.logger).Infow("adjustment proposed", "tenant", tenant, "adjustment_id", adjustment.Id,
+		"user_id", adjustment.UserId, "value", adjustment.Value, "proposed_by", proposer)
+	return adjustment, nil
+}
+
+// Approve posts the adjustment through the balance service, so the usual
+// validation, frozen account and insufficient balance checks apply. The
+// balance change, the posted status and the audit events are stored in one
+// transaction, so a pending adjustment is posted exactly once. If the balance
+// service refuses it, the adjustment is marked as failed and the refusal is
+// returned; on other errors it stays pending and may be approved again.
+func (s *Service) Approve(ctx context.Context, tenant string, id int64) (models.Adjustment, error) {
+	approver, err := operator(ctx)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	adjustment, err := s.get(ctx, tenant, id)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	if adjustment.Status != models.AdjustmentPending {
+		return models.Adjustment{}, fmt.Errorf("adjustment %d is %s: %w", id, adjustment.Status,
+			e.AdjustmentNotPendingError)
+	}
+	if adjustment.ProposedBy == approver {
+		return models.Adjustment{}, e.SelfApprovalError
+	}
+	now := time.Now()
+	if !now.Before(adjustment.ExpiresAt) {
+		_, err = s.transition(ctx, tenant, id, models.AdjustmentPending, models.AdjustmentEvent{
+			Action: models.AdjustmentExpired, Client: approver, Comment: "expired before approval", CreatedAt: now,
+		})
+		if err != nil && !errors.Is(err, e.AdjustmentNotPendingError) {
+			return models.Adjustment{}, err
+		}
+		return models.Adjustment{}, fmt.Errorf("adjustment %d expired at %s: %w", id,
+			adjustment.ExpiresAt.Format(time.RFC3339), e.AdjustmentExpiredError)
+	}
+
+	event := models.AdjustmentEvent{Client: approver, CreatedAt: now}
+	adjustment, err = s.db.PostAdjustment(ctx, tenant, id, event, func(tx ports.Tx,
+		adjustment models.Adjustment) error {
+		operation := models.BalanceWithDesc{
+			UserId:      adjustment.UserId,
+			Value:       adjustment.Value.Abs(),
+			Time:        now,
+			Description: DescriptionPrefix + adjustment.Reason,
+		}
+		if adjustment.Value.IsPositive() {
+			return s.balance.AddIncomeTx(ctx, tx, tenant, operation)
+		}
+		return s.balance.AddExpenseTx(ctx, tx, tenant, operation)
+	})
+	if err != nil {
+		var invalid *e.ValidationError
+		switch {
+		case errors.Is(err, e.UnknownAdjustmentError), errors.Is(err, e.AdjustmentNotPendingError),
+			errors.Is(err, e.DatabaseError):
+			return models.Adjustment{}, err
+		case errors.As(err, &invalid), errors.Is(err, e.UnknownUserIdError),
+			errors.Is(err, e.NotEnoughUserBalanceError), errors.Is(err, e.AccountFrozenError):
+			s.fail(ctx, tenant, id, approver, err)
+			return models.Adjustment{}, err
+		}
+		utils.Logger(ctx, s.logger).Errorw("post adjustment fail", "error", err, "tenant", tenant,
+			"adjustment_id", id)
+		return models.Adjustment{}, e.DatabaseError
+	}
+
+	utils.Logger(ctx, s.logger).Infow("adjustment posted", "tenant", tenant, "adjustment_id", id,
+		"user_id", adjustment.UserId, "value", adjustment.Value, "proposed_by", adjustment.ProposedBy, "approved_by", approver)
+	return adjustment, nil
+}
+
+// fail marks a pending adjustment that was refused as failed.
+func (s *Service) fail(ctx context.Context, tenant string, id int64, approver string, refusal error) {
+	_, err := s.transition(ctx, tenant, id, models.AdjustmentPending, models.AdjustmentEvent{
+		Action: models.AdjustmentFailed, Client: approver, Comment: refusal.Error(), CreatedAt: time.Now(),
+	})
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("adjustment failed but status update failed", "error", err,
+			"adjustment_id", id)
+	}
+}
+
+// Reject closes a pending adjustment without posting it. The proposer may
+// reject their own proposal to withdraw it.
+func (s *Service) Reject(ctx context.Context, tenant string, id int64, comment string) (models.Adjustment, error) {
+	rejecter, err := operator(ctx)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(comment) > MaxCommentLength {
+		return models.Adjustment{}, &e.ValidationError{Fields: []e.FieldError{{Field: "comment",
+			Message: fmt.Sprintf("must be at most %d characters", MaxCommentLength)}}}
+	}
+
+	adjustment, err := s.transition(ctx, tenant, id, models.AdjustmentPending, models.AdjustmentEvent{
+		Action: models.AdjustmentRejected, Client: rejecter, Comment: comment, CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	utils.Logger(ctx, s.logger).Infow("adjustment rejected", "tenant", tenant, "adjustment_id", id,
+		"rejected_by", rejecter)
+	return adjustment, nil
+}
+
+func (s *Service) GetAdjustment(ctx context.Context, tenant string, id int64) (models.Adjustment, error) {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return models.Adjustment{}, e.ForbiddenError
+	}
+	return s.get(ctx, tenant, id)
+}
+
+func (s *Service) ListAdjustments(ctx context.Context, tenant string,
+	filter models.AdjustmentFilter) ([]models.Adjustment, error) {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return nil, e.ForbiddenError
+	}
+	switch filter.Status {
+	case "", models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentPosted,
+		models.AdjustmentFailed, models.AdjustmentRejected, models.AdjustmentExpired:
+	default:
+		return nil, &e.ValidationError{Fields: []e.FieldError{{Field: "status", Message: "is unknown"}}}
+	}
+
+	adjustments, err := s.db.ListAdjustments(ctx, tenant, filter)
+
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("list adjustments fail", "error", err, "tenant", tenant,
+			"user_id", filter.UserId)
+		return nil, e.DatabaseError
+	}
+	return adjustments, nil
+}
+
+// Expire marks pending adjustments past their deadline as expired.
+func (s *Service) Expire(ctx context.Context) (int64, error) {
+	expired, err := s.db.ExpireAdjustments(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("expire adjustments failed: %w", err)
+	}
+	if expired > 0 {
+		s.logger.Infow("adjustments expired", "count", expired)
+	}
+	return expired, nil
+}
+
+// Run expires pending adjustments every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Expire(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Errorf("adjustments expiry fail: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) get(ctx context.Context, tenant string, id int64) (models.Adjustment, error) {
+	adjustment, err := s.db.GetAdjustment(ctx, tenant, id)
+	if err != nil {
+		if errors.Is(err, e.UnknownAdjustmentError) {
+			return models.Adjustment{}, err
+		}
+		utils.Logger(ctx, s.logger).Errorw("get adjustment fail", "error", err, "tenant", tenant,
+			"adjustment_id", id)
+		return models.Adjustment{}, e.DatabaseError
+	}
+	return adjustment, nil
+}
+
+func (s *Service) transition(ctx context.Context, tenant string, id int64, from string,
+	event models.AdjustmentEvent) (models.Adjustment, error) {
+	adjustment, err := s.db.TransitionAdjustment(ctx, tenant, id, from, event)
+	if err != nil {
+		if errors.Is(err, e.UnknownAdjustmentError) || errors.Is(err, e.AdjustmentNotPendingError) {
+			return models.Adjustment{}, err
+		}
+		utils.Logger(ctx, s.logger).Errorw("update adjustment fail", "error", err, "tenant", tenant,
+			"adjustment_id", id, "action", event.Action)
+		return models.Adjustment{}, e.DatabaseError
+	}
+	return adjustment, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+)
+
+type clientKey struct{}
+
+func WithClient(ctx context.Context, client models.Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func ClientFromContext(ctx context.Context) (models.Client, bool) {
+	client, ok := ctx.Value(clientKey{}).(models.Client)
+	return client, ok
+}
+
+func HashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+type Service struct {
+	db     ports.ClientStoragePort
+	logger *zap.SugaredLogger
+}
+
+func New(db ports.ClientStoragePort, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *Service) Authenticate(ctx context.Context, apiKey string) (models.Client, error) {
+	if apiKey == "" {
+		return models.Client{}, e.UnauthenticatedError
+	}
+
+	client, err := s.db.GetClientByKeyHash(ctx, HashKey(apiKey))
+	if err != nil {
+		if errors.Is(err, e.UnknownClientError) {
+			return models.Client{}, e.UnauthenticatedError
+		}
+		s.logger.Errorf("get client fail: %v", err)
+		return models.Client{}, e.DatabaseError
+	}
+	return client, nil
+}
+
+// CreateClient creates a client bound to tenant, or a platform client when
+// tenant is empty. A tenant-bound caller only creates clients of its tenant.
+func (s *Service) CreateClient(ctx context.Context, tenant string, name string,
+	scopes []string) (models.Client, string, error) {
+	tenant, err := callerTenant(ctx, tenant)
+	if err != nil {
+		return models.Client{}, "", err
+	}
+	if name == "" {
+		return models.Client{}, "", fmt.Errorf("name is empty: %w", e.InvalidClientError)
+	}
+	for _, scope := range scopes {
+		if !knownScope(scope) {
+			return models.Client{}, "", fmt.Errorf("scope %q: %w", scope, e.InvalidClientError)
+		}
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		s.logger.Errorf("generate api key fail: %v", err)
+		return models.Client{}, "", e.InternalError
+	}
+	apiKey := hex.EncodeToString(key)
+
+	client, err := s.db.CreateClient(ctx,
+		models.Client{Name: name, Tenant: tenant, Scopes: scopes, CreatedAt: time.Now()}, HashKey(apiKey))
+	if err != nil {
+		s.logger.Errorf("create client fail: %v", err)
+		return models.Client{}, "", e.DatabaseError
+	}
+	return client, apiKey, nil
+}
+
+// ListClients lists the clients of tenant, or all clients when tenant is
+// empty. A tenant-bound caller only sees the clients of its tenant.
+func (s *Service) ListClients(ctx context.Context, tenant string) ([]models.Client, error) {
+	tenant, err := callerTenant(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.db.ListClients(ctx, tenant)
+	if err != nil {
+		s.logger.Errorf("list clients fail: %v", err)
+		return nil, e.DatabaseError
+	}
+	return clients, nil
+}
+
+func callerTenant(ctx context.Context, tenant string) (string, error) {
+	caller, ok := ClientFromContext(ctx)
+	if !ok || caller.Tenant == "" {
+		return tenant, nil
+	}
+	if tenant != "" && tenant != caller.Tenant {
+		return "", fmt.Errorf("client is bound to tenant %q: %w", caller.Tenant, e.ForbiddenError)
+	}
+	return caller.Tenant, nil
+}
+
+func knownScope(scope string) bool {
+	for _, known := range models.Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
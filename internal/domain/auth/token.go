@@ -0,0 +1,174 @@
+package auth
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+)
+
+type userKey struct{}
+
+func WithUser(ctx context.Context, userId int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userId)
+}
+
+func UserFromContext(ctx context.Context) (int64, bool) {
+	userId, ok := ctx.Value(userKey{}).(int64)
+	return userId, ok
+}
+
+func UserClient(userId int64, tenant string) models.Client {
+	return models.Client{Name: "user:" + strconv.FormatInt(userId, 10), Tenant: tenant, Scopes: models.UserScopes}
+}
+
+// tokenClaims are the registered claims plus the tenant of the user. Tokens
+// without the tenant claim are issued for the default tenant.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Tenant string `json:"tenant,omitempty"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type TokenVerifier struct {
+	keys     map[string]interface{}
+	issuer   string
+	audience string
+}
+
+func NewTokenVerifier(jwksFile, issuer, audience string) (*TokenVerifier, error) {
+	data, err := ioutil.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks failed: %w", err)
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("parse jwks failed: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, fmt.Errorf("jwks %s has no keys", jwksFile)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", key.Kid, err)
+		}
+		keys[key.Kid] = publicKey
+	}
+
+	return &TokenVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}, nil
+}
+
+func (v *TokenVerifier) VerifyToken(ctx context.Context, token string) (int64, string, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, v.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	if err != nil {
+		return 0, "", fmt.Errorf("%v: %w", err, e.UnauthenticatedError)
+	}
+	if claims.ExpiresAt == nil {
+		return 0, "", fmt.Errorf("token has no expiration: %w", e.UnauthenticatedError)
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return 0, "", fmt.Errorf("unexpected token issuer: %w", e.UnauthenticatedError)
+	}
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return 0, "", fmt.Errorf("unexpected token audience: %w", e.UnauthenticatedError)
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userId <= 0 {
+		return 0, "", fmt.Errorf("token subject is not a user id: %w", e.UnauthenticatedError)
+	}
+	tenant := claims.Tenant
+	if tenant == "" {
+		tenant = models.DefaultTenant
+	}
+	return userId, tenant, nil
+}
+
+func (v *TokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decode key parameter failed: %w", err)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
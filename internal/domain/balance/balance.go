@@ -1,42 +1,174 @@
 package balance
 
 import (
+	"balance/internal/domain/auth"
 	e "balance/internal/domain/errors"
 	"balance/internal/domain/models"
 	"balance/internal/ports"
+	"balance/internal/utils"
 	"context"
 	"errors"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"time"
 )
 
 type Service struct {
-	db     ports.BalanceStoragePort
-	logger *zap.SugaredLogger
+	db       ports.BalanceStoragePort
+	currency models.Currency
+	tenants  ports.TenantPort
+	rules    models.TransferRules
+	logger   *zap.SugaredLogger
 }
 
-func New(db ports.BalanceStoragePort, logger *zap.SugaredLogger) *Service {
+func New(db ports.BalanceStoragePort, currency models.Currency, logger *zap.SugaredLogger) *Service {
 	return &Service{
-		db:     db,
-		logger: logger,
+		db:       db,
+		currency: currency,
+		logger:   logger,
 	}
 }
 
-func (s *Service) AddIncome(ctx context.Context, transaction models.BalanceWithDesc) error {
-	err := s.db.AddIncome(ctx, transaction)
+// WithTenants takes the currency and operation limits from the tenant
+// settings instead of the single currency the service was created with.
+func (s *Service) WithTenants(tenants ports.TenantPort) {
+	s.tenants = tenants
+}
+
+// WithTransferRules restricts transfers to the allowed pairs of account
+// types, without rules any transfer is allowed.
+func (s *Service) WithTransferRules(rules models.TransferRules) {
+	s.rules = rules
+}
+
+func (s *Service) settings(tenant string) (models.TenantSettings, error) {
+	if s.tenants == nil {
+		return models.TenantSettings{Currency: s.currency}, nil
+	}
+	return s.tenants.Settings(tenant)
+}
+
+func (s *Service) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return models.Account{}, e.ForbiddenError
+	}
+	if err := validateAccount(account); err != nil {
+		return models.Account{}, err
+	}
+	account.CreatedAt = time.Now()
+
+	created, err := s.db.CreateAccount(ctx, tenant, account)
+
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("create account fail", "error", err, "tenant", tenant,
+			"user_id", account.Id, "type", account.Type)
+		if errors.Is(err, e.AccountExistsError) {
+			return models.Account{}, err
+		}
+		return models.Account{}, e.DatabaseError
+	}
+	utils.Logger(ctx, s.logger).Infow("account created", "tenant", tenant, "user_id", created.Id,
+		"type", created.Type)
+	return created, nil
+}
+
+func (s *Service) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return models.Account{}, err
+	}
+	account, err := s.db.GetAccount(ctx, tenant, id)
 
 	if err != nil {
-		s.logger.Errorf("add income fail: %v", err)
+		utils.Logger(ctx, s.logger).Errorw("get account fail", "error", err, "tenant", tenant, "user_id", id)
+		if errors.Is(err, e.UnknownUserIdError) {
+			return models.Account{}, err
+		}
+		return models.Account{}, e.DatabaseError
+	}
+	return account, nil
+}
+
+func (s *Service) AddIncome(ctx context.Context, tenant string, transaction models.BalanceWithDesc) error {
+	return s.addIncome(ctx, nil, tenant, transaction)
+}
+
+// AddIncomeTx credits the account inside tx with the checks of AddIncome.
+func (s *Service) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	transaction models.BalanceWithDesc) error {
+	return s.addIncome(ctx, tx, tenant, transaction)
+}
+
+func (s *Service) addIncome(ctx context.Context, tx ports.Tx, tenant string,
+	transaction models.BalanceWithDesc) error {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return e.ForbiddenError
+	}
+	settings, err := s.settings(tenant)
+	if err != nil {
+		return err
+	}
+	if err := validateBalanceOperation(transaction, settings); err != nil {
+		return err
+	}
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		transaction.Client = client.Name
+	}
+
+	if tx == nil {
+		err = s.db.AddIncome(ctx, tenant, transaction)
+	} else {
+		err = s.db.AddIncomeTx(ctx, tx, tenant, transaction)
+	}
+
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("add income fail", "error", err, "tenant", tenant,
+			"user_id", transaction.UserId)
+		if errors.Is(err, e.AccountFrozenError) {
+			return err
+		}
 		return e.DatabaseError
 	}
 	return nil
 }
 
-func (s *Service) AddExpense(ctx context.Context, transaction models.BalanceWithDesc) error {
-	err := s.db.AddExpense(ctx, transaction)
+func (s *Service) AddExpense(ctx context.Context, tenant string, transaction models.BalanceWithDesc) error {
+	return s.addExpense(ctx, nil, tenant, transaction)
+}
+
+// AddExpenseTx debits the account inside tx with the checks of AddExpense.
+func (s *Service) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	transaction models.BalanceWithDesc) error {
+	return s.addExpense(ctx, tx, tenant, transaction)
+}
+
+func (s *Service) addExpense(ctx context.Context, tx ports.Tx, tenant string,
+	transaction models.BalanceWithDesc) error {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return e.ForbiddenError
+	}
+	settings, err := s.settings(tenant)
+	if err != nil {
+		return err
+	}
+	if err := validateBalanceOperation(transaction, settings); err != nil {
+		return err
+	}
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		transaction.Client = client.Name
+	}
+
+	if tx == nil {
+		err = s.db.AddExpense(ctx, tenant, transaction)
+	} else {
+		err = s.db.AddExpenseTx(ctx, tx, tenant, transaction)
+	}
 
 	if err != nil {
-		s.logger.Errorf("add expense fail: %v", err)
-		if errors.Is(err, e.UnknownUserIdError) || errors.Is(err, e.NotEnoughUserBalanceError) {
+		utils.Logger(ctx, s.logger).Errorw("add expense fail", "error", err, "tenant", tenant,
+			"user_id", transaction.UserId)
+		if errors.Is(err, e.UnknownUserIdError) || errors.Is(err, e.NotEnoughUserBalanceError) ||
+			errors.Is(err, e.AccountFrozenError) {
 			return err
 		}
 		return e.DatabaseError
@@ -44,12 +176,29 @@ func (s *Service) AddExpense(ctx context.Context, transaction models.BalanceWith
 	return nil
 }
 
-func (s *Service) DoTransfer(ctx context.Context, transaction models.Transaction) error {
-	err := s.db.DoTransfer(ctx, transaction)
+func (s *Service) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	if err := authorizeUser(ctx, transaction.UserIdFrom); err != nil {
+		return err
+	}
+	settings, err := s.settings(tenant)
+	if err != nil {
+		return err
+	}
+	if err := validateTransaction(transaction, settings); err != nil {
+		return err
+	}
+	transaction.Rules = s.rules
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		transaction.Client = client.Name
+	}
+
+	err = s.db.DoTransfer(ctx, tenant, transaction)
 
 	if err != nil {
-		s.logger.Errorf("transfer fail: %v", err)
-		if errors.Is(err, e.UnknownUserIdError) || errors.Is(err, e.NotEnoughUserBalanceError) {
+		utils.Logger(ctx, s.logger).Errorw("transfer fail", "error", err, "tenant", tenant,
+			"user_id_from", transaction.UserIdFrom, "user_id_to", transaction.UserIdTo)
+		if errors.Is(err, e.UnknownUserIdError) || errors.Is(err, e.NotEnoughUserBalanceError) ||
+			errors.Is(err, e.AccountFrozenError) || errors.Is(err, e.TransferNotAllowedError) {
 			return err
 		}
 		return e.DatabaseError
@@ -57,11 +206,14 @@ func (s *Service) DoTransfer(ctx context.Context, transaction models.Transaction
 	return nil
 }
 
-func (s *Service) GetBalance(ctx context.Context, userId int64) (models.Balance, error) {
-	balance, err := s.db.GetBalance(ctx, userId)
+func (s *Service) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	if err := authorizeUser(ctx, userId); err != nil {
+		return models.Balance{}, err
+	}
+	balance, err := s.db.GetBalance(ctx, tenant, userId)
 
 	if err != nil {
-		s.logger.Errorf("get balance fail: %v", err)
+		utils.Logger(ctx, s.logger).Errorw("get balance fail", "error", err, "tenant", tenant, "user_id", userId)
 		if errors.Is(err, e.UnknownUserIdError) {
 			return models.Balance{}, err
 		}
@@ -69,3 +221,117 @@ func (s *Service) GetBalance(ctx context.Context, userId int64) (models.Balance,
 	}
 	return balance, nil
 }
+
+func (s *Service) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	w ports.StatementWriter) error {
+	settings, err := s.settings(tenant)
+	if err != nil {
+		return err
+	}
+	_, err = s.GetBalance(ctx, tenant, userId)
+	if err != nil {
+		return err
+	}
+
+	var running decimal.Decimal
+	var writeErr error
+	header := func(opening, closing decimal.Decimal) error {
+		running = opening
+		writeErr = w.WriteHeader(models.Statement{
+			UserId:         userId,
+			Currency:       settings.Currency,
+			From:           from,
+			To:             to,
+			OpeningBalance: opening,
+			ClosingBalance: closing,
+		})
+		return writeErr
+	}
+	err = s.db.GetStatement(ctx, tenant, userId, from, to, header, func(transaction models.Transaction) error {
+		amount := decimal.Zero
+		if transaction.UserIdTo == userId {
+			amount = amount.Add(transaction.Value)
+		}
+		if transaction.UserIdFrom == userId {
+			amount = amount.Sub(transaction.Value)
+		}
+		running = running.Add(amount)
+
+		writeErr = w.WriteEntry(models.StatementEntry{
+			Transaction:    transaction,
+			Amount:         amount,
+			RunningBalance: running,
+		})
+		return writeErr
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("get statement fail", "error", err, "user_id", userId)
+		return e.DatabaseError
+	}
+
+	return w.Close()
+}
+
+func (s *Service) GetHistory(ctx context.Context, tenant string, userId int64,
+	from, to time.Time) ([]models.Transaction, error) {
+	_, err := s.GetBalance(ctx, tenant, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.Transaction{}
+	err = s.db.GetHistory(ctx, tenant, userId, from, to, func(transaction models.Transaction) error {
+		history = append(history, transaction)
+		return nil
+	})
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("get history fail", "error", err, "user_id", userId)
+		return nil, e.DatabaseError
+	}
+	return history, nil
+}
+
+// SetFrozen blocks or unblocks every balance change of the account.
+func (s *Service) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return e.ForbiddenError
+	}
+
+	err := s.db.SetFrozen(ctx, tenant, userId, frozen)
+
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("set frozen fail", "error", err, "tenant", tenant, "user_id", userId,
+			"frozen", frozen)
+		if errors.Is(err, e.UnknownUserIdError) {
+			return err
+		}
+		return e.DatabaseError
+	}
+	utils.Logger(ctx, s.logger).Infow("account frozen state changed", "tenant", tenant, "user_id", userId,
+		"frozen", frozen)
+	return nil
+}
+
+func (s *Service) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	if _, ok := auth.UserFromContext(ctx); ok {
+		return models.Reconciliation{}, e.ForbiddenError
+	}
+
+	reconciliation, err := s.db.Reconcile(ctx, tenant)
+
+	if err != nil {
+		utils.Logger(ctx, s.logger).Errorw("reconcile fail", "error", err, "tenant", tenant)
+		return models.Reconciliation{}, e.DatabaseError
+	}
+	return reconciliation, nil
+}
+
+func authorizeUser(ctx context.Context, userId int64) error {
+	if user, ok := auth.UserFromContext(ctx); ok && user != userId {
+		return e.ForbiddenError
+	}
+	return nil
+}
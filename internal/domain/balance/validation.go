@@ -0,0 +1,87 @@
+package balance
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"unicode/utf8"
+)
+
+const (
+	MaxDescriptionLength = 255
+	MaxOwnerRefLength    = 255
+)
+
+type validator struct {
+	scale  int32
+	max    decimal.Decimal
+	fields []e.FieldError
+}
+
+func (v *validator) add(field, message string) {
+	v.fields = append(v.fields, e.FieldError{Field: field, Message: message})
+}
+
+func (v *validator) userId(field string, userId int64) {
+	if userId <= 0 {
+		v.add(field, "must be positive")
+	}
+}
+
+func (v *validator) amount(field string, value decimal.Decimal) {
+	if !value.IsPositive() {
+		v.add(field, "must be positive")
+	}
+	if !value.Equal(value.Truncate(v.scale)) {
+		v.add(field, fmt.Sprintf("must have at most %d decimal places", v.scale))
+	}
+	if !v.max.IsZero() && value.GreaterThan(v.max) {
+		v.add(field, "must be at most "+v.max.String())
+	}
+}
+
+func (v *validator) description(field, description string) {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		v.add(field, fmt.Sprintf("must be at most %d characters", MaxDescriptionLength))
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &e.ValidationError{Fields: v.fields}
+}
+
+func validateBalanceOperation(operation models.BalanceWithDesc, settings models.TenantSettings) error {
+	v := &validator{scale: settings.Currency.Scale, max: settings.MaxOperation}
+	v.userId("user_id", operation.UserId)
+	v.amount("value", operation.Value)
+	v.description("description", operation.Description)
+	return v.err()
+}
+
+func validateTransaction(transaction models.Transaction, settings models.TenantSettings) error {
+	v := &validator{scale: settings.Currency.Scale, max: settings.MaxOperation}
+	v.userId("user_id_from", transaction.UserIdFrom)
+	v.userId("user_id_to", transaction.UserIdTo)
+	if transaction.UserIdFrom == transaction.UserIdTo {
+		v.add("user_id_to", "must differ from user_id_from")
+	}
+	v.amount("value", transaction.Value)
+	v.description("description", transaction.Description)
+	return v.err()
+}
+
+func validateAccount(account models.Account) error {
+	v := &validator{}
+	v.userId("id", account.Id)
+	if !account.Type.Valid() {
+		v.add("type", "must be user, merchant or system")
+	}
+	if utf8.RuneCountInString(account.OwnerRef) > MaxOwnerRefLength {
+		v.add("owner_ref", fmt.Sprintf("must be at most %d characters", MaxOwnerRefLength))
+	}
+	return v.err()
+}
@@ -1,11 +1,62 @@
 package errors
 
-import (
-	"errors"
-)
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
 
 var (
-	UnknownUserIdError        = errors.New("user_id does not exist")
-	NotEnoughUserBalanceError = errors.New("user_id has not enough balance")
-	DatabaseError             = errors.New("database error")
+	UnknownUserIdError        = &Error{Code: "unknown_user_id", Message: "user_id does not exist"}
+	NotEnoughUserBalanceError = &Error{Code: "insufficient_balance", Message: "user_id has not enough balance"}
+	DatabaseError             = &Error{Code: "database_error", Message: "database error"}
+	InternalError             = &Error{Code: "internal_error", Message: "internal server error"}
+	UnknownWebhookError       = &Error{Code: "unknown_webhook", Message: "webhook subscription does not exist"}
+	UnknownDeadLetterError    = &Error{Code: "unknown_dead_letter", Message: "dead letter does not exist"}
+	InvalidWebhookError       = &Error{Code: "invalid_webhook", Message: "webhook subscription is invalid"}
+	InvalidRequestError       = &Error{Code: "invalid_request", Message: "request is invalid"}
+	ValidationFailedError     = &Error{Code: "validation_failed", Message: "operation validation failed"}
+	UnauthenticatedError      = &Error{Code: "unauthenticated", Message: "client is not authenticated"}
+	ForbiddenError            = &Error{Code: "forbidden", Message: "client is not allowed to perform the operation"}
+	UnknownClientError        = &Error{Code: "unknown_client", Message: "client does not exist"}
+	InvalidClientError        = &Error{Code: "invalid_client", Message: "client is invalid"}
+	RateLimitedError          = &Error{Code: "rate_limited", Message: "too many requests"}
+	OverloadedError           = &Error{Code: "overloaded", Message: "service is overloaded"}
+	AccountFrozenError        = &Error{Code: "account_frozen", Message: "user_id account is frozen"}
+	UnknownAdjustmentError    = &Error{Code: "unknown_adjustment", Message: "adjustment does not exist"}
+	AdjustmentNotPendingError = &Error{Code: "adjustment_not_pending", Message: "adjustment is not pending"}
+	AdjustmentExpiredError    = &Error{Code: "adjustment_expired", Message: "adjustment has expired"}
+	SelfApprovalError         = &Error{Code: "self_approval", Message: "adjustment cannot be approved by its proposer"}
+	UnknownTenantError        = &Error{Code: "unknown_tenant", Message: "tenant does not exist"}
+	AccountExistsError        = &Error{Code: "account_exists", Message: "account already exists"}
+	TransferNotAllowedError   = &Error{Code: "transfer_not_allowed", Message: "account types do not allow the transfer"}
 )
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (v *ValidationError) Error() string {
+	msg := ValidationFailedError.Message
+	for i, field := range v.Fields {
+		if i == 0 {
+			msg += ": "
+		} else {
+			msg += "; "
+		}
+		msg += field.Field + " " + field.Message
+	}
+	return msg
+}
+
+func (v *ValidationError) Unwrap() error {
+	return ValidationFailedError
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Check func(ctx context.Context) error
+
+type component struct {
+	name  string
+	check Check
+}
+
+type Service struct {
+	components []component
+	timeout    time.Duration
+}
+
+func New(timeout time.Duration) *Service {
+	return &Service{timeout: timeout}
+}
+
+func (s *Service) Register(name string, check Check) {
+	s.components = append(s.components, component{name: name, check: check})
+}
+
+func (s *Service) Ready(ctx context.Context) models.Health {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	health := models.Health{
+		Status:     models.HealthOk,
+		Components: make(map[string]models.ComponentHealth, len(s.components)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range s.components {
+		wg.Add(1)
+		go func(c component) {
+			defer wg.Done()
+			result := models.ComponentHealth{Status: models.HealthOk}
+			if err := c.check(ctx); err != nil {
+				result = models.ComponentHealth{Status: models.HealthFail, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			health.Components[c.name] = result
+			if result.Status != models.HealthOk {
+				health.Status = models.HealthFail
+			}
+		}(c)
+	}
+	wg.Wait()
+	return health
+}
+
+func DatabaseCheck(db ports.HealthStoragePort) Check {
+	return db.Ping
+}
+
+func MigrationCheck(db ports.HealthStoragePort, expected int64) Check {
+	return func(ctx context.Context) error {
+		version, err := db.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version != expected {
+			return fmt.Errorf("applied migration version %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
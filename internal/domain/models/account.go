@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AccountType string
+
+const (
+	AccountUser     AccountType = "user"
+	AccountMerchant AccountType = "merchant"
+	AccountSystem   AccountType = "system"
+)
+
+var AccountTypes = []AccountType{AccountUser, AccountMerchant, AccountSystem}
+
+func (t AccountType) Valid() bool {
+	for _, accountType := range AccountTypes {
+		if t == accountType {
+			return true
+		}
+	}
+	return false
+}
+
+// Account is the owner of a balance. Its id is the user_id of the balance
+// API; accounts created implicitly by an income or a transfer are user
+// accounts without an owner reference.
+type Account struct {
+	Id        int64       `json:"id"`
+	Type      AccountType `json:"type"`
+	OwnerRef  string      `json:"owner_ref,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// DefaultTransferRules allows transfers between any account types except
+// from users to system accounts.
+const DefaultTransferRules = "user:user,user:merchant,merchant:user,merchant:merchant,merchant:system," +
+	"system:user,system:merchant,system:system"
+
+// TransferRules lists the account type pairs a transfer may go between.
+type TransferRules map[AccountType]map[AccountType]bool
+
+// ParseTransferRules parses comma separated from:to pairs of account types.
+func ParseTransferRules(raw string) (TransferRules, error) {
+	rules := TransferRules{}
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		types := strings.SplitN(pair, ":", 2)
+		if len(types) != 2 {
+			return nil, fmt.Errorf("expected from:to pairs, got %q", pair)
+		}
+		from, to := AccountType(strings.TrimSpace(types[0])), AccountType(strings.TrimSpace(types[1]))
+		if !from.Valid() || !to.Valid() {
+			return nil, fmt.Errorf("unknown account type in %q", pair)
+		}
+		if rules[from] == nil {
+			rules[from] = map[AccountType]bool{}
+		}
+		rules[from][to] = true
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no transfer rules in %q", raw)
+	}
+	return rules, nil
+}
+
+func (r TransferRules) Allows(from, to AccountType) bool {
+	return r[from][to]
+}
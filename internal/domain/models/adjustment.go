@@ -0,0 +1,56 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Adjustment statuses. A proposal is pending until another operator approves,
+// rejects it or it expires. Approval posts it to the balance in the same
+// transaction, or marks it as failed when the balance refuses it, so approved
+// is only recorded in the audit trail.
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentPosted   = "posted"
+	AdjustmentFailed   = "failed"
+	AdjustmentRejected = "rejected"
+	AdjustmentExpired  = "expired"
+)
+
+// AdjustmentProposed is the audit action recorded when an adjustment is
+// created; the other actions are the statuses it moves to.
+const AdjustmentProposed = "proposed"
+
+// Adjustment is a manual correction of a balance proposed by one operator and
+// approved by another. A positive value credits the account, a negative one
+// debits it.
+type Adjustment struct {
+	Id            int64             `json:"id"`
+	UserId        int64             `json:"user_id"`
+	Value         decimal.Decimal   `json:"value"`
+	Reason        string            `json:"reason"`
+	AttachmentRef string            `json:"attachment_ref,omitempty"`
+	Status        string            `json:"status"`
+	ProposedBy    string            `json:"proposed_by"`
+	DecidedBy     string            `json:"decided_by,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	DecidedAt     *time.Time        `json:"decided_at,omitempty"`
+	Events        []AdjustmentEvent `json:"events,omitempty"`
+}
+
+// AdjustmentEvent is an entry of the adjustment audit trail.
+type AdjustmentEvent struct {
+	Id           int64     `json:"id"`
+	AdjustmentId int64     `json:"adjustment_id"`
+	Action       string    `json:"action"`
+	Client       string    `json:"client,omitempty"`
+	Comment      string    `json:"comment,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AdjustmentFilter struct {
+	UserId int64
+	Status string
+}
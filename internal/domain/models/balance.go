@@ -8,11 +8,13 @@ import (
 type Balance struct {
 	UserId int64           `json:"user_id"`
 	Value  decimal.Decimal `json:"value"`
+	Frozen bool            `json:"frozen"`
 }
 
 type BalanceWithDesc struct {
 	UserId      int64           `json:"user_id"`
 	Value       decimal.Decimal `json:"value"`
-	Time        time.Time
-	Description string `json:"description"`
+	Time        time.Time       `json:"-"`
+	Description string          `json:"description"`
+	Client      string          `json:"-"`
 }
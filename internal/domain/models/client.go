@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ScopeIncome     = "income"
+	ScopeExpense    = "expense"
+	ScopeTransfer   = "transfer"
+	ScopeBalance    = "balance"
+	ScopeStatements = "statements"
+	ScopeWebhooks   = "webhooks"
+	ScopeAdmin      = "admin"
+)
+
+var Scopes = []string{ScopeIncome, ScopeExpense, ScopeTransfer, ScopeBalance, ScopeStatements, ScopeWebhooks, ScopeAdmin}
+
+var UserScopes = []string{ScopeTransfer, ScopeBalance, ScopeStatements}
+
+// Client is an API key holder. A client with a tenant is bound to it, a client
+// without one is a platform client that chooses the tenant per request.
+type Client struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c Client) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"fmt"
+)
+
+type Currency struct {
+	Code  string
+	Scale int32
+}
+
+var currencies = map[string]Currency{
+	"RUB": {Code: "RUB", Scale: 2},
+	"USD": {Code: "USD", Scale: 2},
+	"EUR": {Code: "EUR", Scale: 2},
+	"KZT": {Code: "KZT", Scale: 2},
+	"JPY": {Code: "JPY", Scale: 0},
+	"KWD": {Code: "KWD", Scale: 3},
+}
+
+func CurrencyByCode(code string) (Currency, error) {
+	currency, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("unsupported currency %q", code)
+	}
+	return currency, nil
+}
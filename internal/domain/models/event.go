@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type EventType string
+
+const (
+	BalanceCredited   EventType = "BalanceCredited"
+	BalanceDebited    EventType = "BalanceDebited"
+	TransferCompleted EventType = "TransferCompleted"
+)
+
+type Event struct {
+	Id        int64           `json:"id"`
+	Tenant    string          `json:"tenant"`
+	Type      EventType       `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type BalanceChangedPayload struct {
+	HistoryId   int64           `json:"history_id"`
+	UserId      int64           `json:"user_id"`
+	Value       decimal.Decimal `json:"value"`
+	Description string          `json:"description"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+type TransferCompletedPayload struct {
+	HistoryId   int64           `json:"history_id"`
+	UserIdFrom  int64           `json:"user_id_from"`
+	UserIdTo    int64           `json:"user_id_to"`
+	Value       decimal.Decimal `json:"value"`
+	Description string          `json:"description"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
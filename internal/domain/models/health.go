@@ -0,0 +1,16 @@
+package models
+
+const (
+	HealthOk   = "ok"
+	HealthFail = "fail"
+)
+
+type ComponentHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Health struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import (
+	"time"
+)
+
+type OutboxStats struct {
+	Pending       int64
+	OldestPending time.Time
+}
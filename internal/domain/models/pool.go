@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type PoolStats struct {
+	AcquireCount    int64
+	AcquireDuration time.Duration
+	AcquiredConns   int32
+	IdleConns       int32
+	TotalConns      int32
+	MaxConns        int32
+}
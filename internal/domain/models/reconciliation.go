@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Reconciliation compares every stored balance with the sum of its history.
+type Reconciliation struct {
+	Accounts   int64                    `json:"accounts"`
+	Mismatches []ReconciliationMismatch `json:"mismatches"`
+}
+
+type ReconciliationMismatch struct {
+	UserId         int64           `json:"user_id"`
+	Balance        decimal.Decimal `json:"balance"`
+	HistoryBalance decimal.Decimal `json:"history_balance"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type Statement struct {
+	UserId         int64
+	Currency       Currency
+	From           time.Time
+	To             time.Time
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+}
+
+type StatementEntry struct {
+	Transaction
+	Amount         decimal.Decimal
+	RunningBalance decimal.Decimal
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTenant owns requests that do not name a tenant and all data created
+// before multi-tenancy.
+const DefaultTenant = "default"
+
+type TenantSettings struct {
+	Currency Currency
+	// MaxOperation caps the value of a single income, expense or transfer,
+	// zero means no cap.
+	MaxOperation decimal.Decimal
+}
@@ -10,6 +10,10 @@ type Transaction struct {
 	UserIdFrom  int64           `json:"user_id_from"`
 	UserIdTo    int64           `json:"user_id_to"`
 	Value       decimal.Decimal `json:"value"`
-	Time        time.Time
-	Description string `json:"description"`
+	Time        time.Time       `json:"-"`
+	Description string          `json:"description"`
+	Client      string          `json:"-"`
+	// Rules are checked by the storage against the locked accounts, nil
+	// allows transfers between any account types.
+	Rules TransferRules `json:"-"`
 }
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+type WebhookSubscription struct {
+	Id         int64       `json:"id"`
+	Url        string      `json:"url"`
+	Secret     string      `json:"secret,omitempty"`
+	EventTypes []EventType `json:"event_types"`
+	UserId     *int64      `json:"user_id,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type DeadLetter struct {
+	Id             int64      `json:"id"`
+	SubscriptionId int64      `json:"subscription_id"`
+	Event          Event      `json:"event"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	FailedAt       time.Time  `json:"failed_at"`
+	ReplayedAt     *time.Time `json:"replayed_at,omitempty"`
+}
+
+// WebhookDelivery is an event queued for a subscription until it is delivered
+// or moved to the dead letters.
+type WebhookDelivery struct {
+	Id            int64               `json:"id"`
+	Subscription  WebhookSubscription `json:"subscription"`
+	Event         Event               `json:"event"`
+	Attempts      int                 `json:"attempts"`
+	LastError     string              `json:"last_error,omitempty"`
+	NextAttemptAt time.Time           `json:"next_attempt_at"`
+}
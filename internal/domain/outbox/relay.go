@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const defaultStopTimeout = 10 * time.Second
+
+type Relay struct {
+	storage     ports.OutboxStoragePort
+	publisher   ports.EventPublisher
+	logger      *zap.SugaredLogger
+	interval    time.Duration
+	batchSize   int
+	stopTimeout time.Duration
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+}
+
+func New(storage ports.OutboxStoragePort, publisher ports.EventPublisher, logger *zap.SugaredLogger,
+	interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		storage:     storage,
+		publisher:   publisher,
+		logger:      logger,
+		interval:    interval,
+		batchSize:   batchSize,
+		stopTimeout: defaultStopTimeout,
+	}
+}
+
+// WithStopTimeout sets how long a batch that is being delivered when Run is
+// stopped may take to finish, after that its delivery is cancelled.
+func (r *Relay) WithStopTimeout(timeout time.Duration) {
+	r.stopTimeout = timeout
+}
+
+// Run polls the outbox until ctx is cancelled. A batch that is being
+// delivered when ctx is cancelled is finished first, within the stop timeout.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.setRunning(true)
+	defer r.setRunning(false)
+
+	deliveryCtx, cancelDelivery := drainContext(ctx, r.stopTimeout)
+	defer cancelDelivery()
+	for {
+		var err error
+		for {
+			var delivered int
+			delivered, err = r.Deliver(deliveryCtx)
+			if err != nil {
+				r.logger.Errorf("outbox delivery fail: %v", err)
+				break
+			}
+			if delivered < r.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		r.recordRun()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) Deliver(ctx context.Context) (int, error) {
+	return r.storage.ProcessEvents(ctx, r.batchSize, func(event models.Event) error {
+		return r.publisher.Publish(ctx, event)
+	})
+}
+
+// Check reports whether the relay is running and has polled the outbox
+// recently. Failed deliveries are retried on the next poll, they are logged
+// and counted by the delivery metrics but do not make the service unready.
+func (r *Relay) Check(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case !r.running:
+		return errors.New("relay is not running")
+	case r.lastRun.IsZero():
+		return errors.New("relay has not polled the outbox yet")
+	case time.Since(r.lastRun) > 3*r.interval+time.Minute:
+		return fmt.Errorf("relay last polled the outbox at %s", r.lastRun.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (r *Relay) setRunning(running bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = running
+}
+
+func (r *Relay) recordRun() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastRun = time.Now()
+}
+
+// drainContext returns a context with the values of ctx that is cancelled
+// timeout after ctx is done.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drain, cancel := context.WithCancel(detachedContext{ctx})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-drain.Done():
+			return
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-drain.Done():
+		}
+	}()
+	return drain, cancel
+}
+
+// detachedContext keeps the values of its parent but is never cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
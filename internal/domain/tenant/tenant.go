@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"fmt"
+)
+
+type tenantKey struct{}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant resolved for the request, or the default
+// tenant when none was resolved.
+func FromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	return models.DefaultTenant
+}
+
+// Registry holds the settings of the configured tenants. The default tenant
+// always exists.
+type Registry struct {
+	tenants map[string]models.TenantSettings
+}
+
+// NewRegistry registers the tenants with their settings. The default tenant
+// gets defaults unless it is configured explicitly.
+func NewRegistry(defaults models.TenantSettings, tenants map[string]models.TenantSettings) *Registry {
+	registry := &Registry{tenants: map[string]models.TenantSettings{models.DefaultTenant: defaults}}
+	for tenant, settings := range tenants {
+		registry.tenants[tenant] = settings
+	}
+	return registry
+}
+
+func (r *Registry) Settings(tenant string) (models.TenantSettings, error) {
+	settings, ok := r.tenants[tenant]
+	if !ok {
+		return models.TenantSettings{}, fmt.Errorf("tenant %q: %w", tenant, e.UnknownTenantError)
+	}
+	return settings, nil
+}
+
+// Resolve returns the requested tenant, or the tenant the caller is bound to,
+// or the default tenant. Empty bound values are ignored; a caller bound to
+// different tenants, or requesting a tenant other than its own, is forbidden.
+func (r *Registry) Resolve(requested string, bound ...string) (string, error) {
+	var tenant string
+	for _, b := range bound {
+		if b == "" {
+			continue
+		}
+		if tenant != "" && b != tenant {
+			return "", fmt.Errorf("credentials are bound to tenants %q and %q: %w", tenant, b, e.ForbiddenError)
+		}
+		tenant = b
+	}
+	if requested != "" {
+		if tenant != "" && requested != tenant {
+			return "", fmt.Errorf("credentials are bound to tenant %q: %w", tenant, e.ForbiddenError)
+		}
+		tenant = requested
+	}
+	if tenant == "" {
+		tenant = models.DefaultTenant
+	}
+	if _, err := r.Settings(tenant); err != nil {
+		return "", err
+	}
+	return tenant, nil
+}
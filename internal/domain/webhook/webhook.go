@@ -0,0 +1,356 @@
+package webhook
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Balance-Signature"
+	TimestampHeader = "X-Balance-Timestamp"
+	EventHeader     = "X-Balance-Event"
+	EventIdHeader   = "X-Balance-Event-Id"
+
+	deliveryBatchSize = 20
+	deliveryLease     = time.Minute
+	maxBackoff        = time.Hour
+)
+
+type Service struct {
+	db          ports.WebhookStoragePort
+	client      *http.Client
+	logger      *zap.SugaredLogger
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func New(db ports.WebhookStoragePort, client *http.Client, logger *zap.SugaredLogger,
+	maxAttempts int, backoff time.Duration) *Service {
+	return &Service{
+		db:          db,
+		client:      client,
+		logger:      logger,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) Subscribe(ctx context.Context, tenant string,
+	subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	target, err := url.Parse(subscription.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return models.WebhookSubscription{}, fmt.Errorf("url %q: %w", subscription.Url, e.InvalidWebhookError)
+	}
+	for _, eventType := range subscription.EventTypes {
+		switch eventType {
+		case models.BalanceCredited, models.BalanceDebited, models.TransferCompleted:
+		default:
+			return models.WebhookSubscription{}, fmt.Errorf("event type %q: %w", eventType, e.InvalidWebhookError)
+		}
+	}
+
+	if subscription.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			s.logger.Errorf("generate webhook secret fail: %v", err)
+			return models.WebhookSubscription{}, e.DatabaseError
+		}
+		subscription.Secret = hex.EncodeToString(secret)
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []models.EventType{}
+	}
+	subscription.CreatedAt = time.Now()
+
+	created, err := s.db.CreateSubscription(ctx, tenant, subscription)
+	if err != nil {
+		s.logger.Errorf("create webhook subscription fail: %v", err)
+		return models.WebhookSubscription{}, e.DatabaseError
+	}
+	return created, nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.db.ListSubscriptions(ctx, tenant)
+	if err != nil {
+		s.logger.Errorf("list webhook subscriptions fail: %v", err)
+		return nil, e.DatabaseError
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (s *Service) Unsubscribe(ctx context.Context, tenant string, id int64) error {
+	err := s.db.DeleteSubscription(ctx, tenant, id)
+	if err != nil {
+		s.logger.Errorf("delete webhook subscription fail: %v", err)
+		if errors.Is(err, e.UnknownWebhookError) {
+			return err
+		}
+		return e.DatabaseError
+	}
+	return nil
+}
+
+func (s *Service) ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error) {
+	deadLetters, err := s.db.ListDeadLetters(ctx, tenant)
+	if err != nil {
+		s.logger.Errorf("list dead letters fail: %v", err)
+		return nil, e.DatabaseError
+	}
+	return deadLetters, nil
+}
+
+// Replay queues the dead letter for delivery again, the worker sends it with
+// fresh attempts and dead letters it again if it still fails.
+func (s *Service) Replay(ctx context.Context, tenant string, deadLetterId int64) error {
+	deadLetter, err := s.db.GetDeadLetter(ctx, tenant, deadLetterId)
+	if err != nil {
+		s.logger.Errorf("get dead letter fail: %v", err)
+		if errors.Is(err, e.UnknownDeadLetterError) {
+			return err
+		}
+		return e.DatabaseError
+	}
+	subscription, err := s.db.GetSubscription(ctx, tenant, deadLetter.SubscriptionId)
+	if err != nil {
+		s.logger.Errorf("get webhook subscription fail: %v", err)
+		if errors.Is(err, e.UnknownWebhookError) {
+			return err
+		}
+		return e.DatabaseError
+	}
+
+	now := time.Now()
+	err = s.db.AddDeliveries(ctx, tenant, []models.WebhookDelivery{{
+		Subscription:  subscription,
+		Event:         deadLetter.Event,
+		NextAttemptAt: now,
+	}})
+	if err != nil {
+		s.logger.Errorf("add webhook delivery fail: %v", err)
+		return e.DatabaseError
+	}
+
+	deadLetter.ReplayedAt = &now
+	if err = s.db.UpdateDeadLetter(ctx, tenant, deadLetter); err != nil {
+		s.logger.Errorf("update dead letter fail: %v", err)
+		return e.DatabaseError
+	}
+	return nil
+}
+
+// Publish queues the event for the matching subscriptions of the tenant it
+// happened in, the deliveries are sent by Run. The relay calls it while it
+// holds the outbox rows, so it does not send anything itself. The deliveries
+// are written outside of the outbox transaction, a redelivered event is not
+// queued twice.
+func (s *Service) Publish(ctx context.Context, event models.Event) error {
+	subscriptions, err := s.db.ListSubscriptions(ctx, event.Tenant)
+	if err != nil {
+		return fmt.Errorf("list webhook subscriptions failed: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		if matches(subscription, event) {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				Subscription:  subscription,
+				Event:         event,
+				NextAttemptAt: now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err = s.db.AddDeliveries(ctx, event.Tenant, deliveries); err != nil {
+		return fmt.Errorf("add webhook deliveries failed: %w", err)
+	}
+	return nil
+}
+
+// SendDeliveries makes one attempt for each due delivery and returns how many
+// it attempted. A failed delivery is retried after a backoff that doubles with
+// every attempt and moved to the dead letters after maxAttempts.
+func (s *Service) SendDeliveries(ctx context.Context) (int, error) {
+	now := time.Now()
+	// the claim outlives the slowest batch, so other workers do not send it twice
+	lease := now.Add(deliveryBatchSize*s.client.Timeout + deliveryLease)
+	deliveries, err := s.db.ClaimDeliveries(ctx, now, lease, deliveryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim webhook deliveries failed: %w", err)
+	}
+
+	for i, delivery := range deliveries {
+		if err = s.attempt(ctx, delivery); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (s *Service) attempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	tenant := delivery.Event.Tenant
+	body, err := json.Marshal(delivery.Event)
+	if err == nil {
+		err = s.send(ctx, delivery.Subscription, delivery.Event, body)
+	}
+	if err == nil {
+		if err = s.db.DeleteDelivery(ctx, tenant, delivery.Id); err != nil {
+			return fmt.Errorf("delete webhook delivery failed: %w", err)
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		// the claim expires and the delivery is sent again later
+		return ctx.Err()
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	s.logger.Errorf("webhook %d delivery of event %d attempt %d fail: %v", delivery.Subscription.Id,
+		delivery.Event.Id, delivery.Attempts, err)
+
+	if delivery.Attempts >= s.maxAttempts {
+		err = s.db.DeadLetterDelivery(ctx, tenant, delivery.Id, models.DeadLetter{
+			SubscriptionId: delivery.Subscription.Id,
+			Event:          delivery.Event,
+			Attempts:       delivery.Attempts,
+			LastError:      delivery.LastError,
+			FailedAt:       time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("add dead letter failed: %w", err)
+		}
+		return nil
+	}
+
+	backoff := s.backoff << (delivery.Attempts - 1)
+	if backoff < s.backoff || backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	delivery.NextAttemptAt = time.Now().Add(backoff)
+	if err = s.db.RetryDelivery(ctx, tenant, delivery); err != nil {
+		return fmt.Errorf("retry webhook delivery failed: %w", err)
+	}
+	return nil
+}
+
+// Run sends due deliveries every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			attempted, err := s.SendDeliveries(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Errorf("webhook deliveries fail: %v", err)
+			}
+			// a full batch means more deliveries may be due
+			if err != nil || attempted < deliveryBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) send(ctx context.Context, subscription models.WebhookSubscription, event models.Event,
+	body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(EventIdHeader, strconv.FormatInt(event.Id, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func matches(subscription models.WebhookSubscription, event models.Event) bool {
+	if len(subscription.EventTypes) > 0 {
+		found := false
+		for _, eventType := range subscription.EventTypes {
+			if eventType == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if subscription.UserId == nil {
+		return true
+	}
+	for _, userId := range eventUserIds(event) {
+		if userId == *subscription.UserId {
+			return true
+		}
+	}
+	return false
+}
+
+func eventUserIds(event models.Event) []int64 {
+	if event.Type == models.TransferCompleted {
+		var payload models.TransferCompletedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil
+		}
+		return []int64{payload.UserIdFrom, payload.UserIdTo}
+	}
+
+	var payload models.BalanceChangedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil
+	}
+	return []int64{payload.UserId}
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type AdjustmentPort interface {
+	Propose(ctx context.Context, tenant string, adjustment models.Adjustment) (models.Adjustment, error)
+	Approve(ctx context.Context, tenant string, id int64) (models.Adjustment, error)
+	Reject(ctx context.Context, tenant string, id int64, comment string) (models.Adjustment, error)
+	GetAdjustment(ctx context.Context, tenant string, id int64) (models.Adjustment, error)
+	ListAdjustments(ctx context.Context, tenant string, filter models.AdjustmentFilter) ([]models.Adjustment, error)
+}
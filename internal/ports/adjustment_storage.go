@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"time"
+)
+
+type AdjustmentStoragePort interface {
+	// AddAdjustment stores a pending adjustment together with its proposed
+	// audit event.
+	AddAdjustment(ctx context.Context, tenant string, adjustment models.Adjustment) (models.Adjustment, error)
+	GetAdjustment(ctx context.Context, tenant string, id int64) (models.Adjustment, error)
+	ListAdjustments(ctx context.Context, tenant string, filter models.AdjustmentFilter) ([]models.Adjustment, error)
+	// TransitionAdjustment moves the adjustment from the given status to
+	// event.Action and appends the event to the audit trail. It fails with
+	// AdjustmentNotPendingError if the adjustment is no longer in status from.
+	TransitionAdjustment(ctx context.Context, tenant string, id int64, from string,
+		event models.AdjustmentEvent) (models.Adjustment, error)
+	// PostAdjustment locks a pending adjustment and calls post to change the
+	// balance inside the transaction, then moves the adjustment to posted and
+	// appends the approved and posted events in the same transaction. An error
+	// of post rolls everything back and is returned as is. It fails with
+	// AdjustmentNotPendingError if the adjustment is no longer pending.
+	PostAdjustment(ctx context.Context, tenant string, id int64, event models.AdjustmentEvent,
+		post func(tx Tx, adjustment models.Adjustment) error) (models.Adjustment, error)
+	// ExpireAdjustments expires pending adjustments with expires_at before now
+	// in all tenants.
+	ExpireAdjustments(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type AuthPort interface {
+	Authenticate(ctx context.Context, apiKey string) (models.Client, error)
+	CreateClient(ctx context.Context, tenant string, name string, scopes []string) (models.Client, string, error)
+	ListClients(ctx context.Context, tenant string) ([]models.Client, error)
+}
@@ -3,11 +3,22 @@ package ports
 import (
 	"balance/internal/domain/models"
 	"context"
+	"time"
 )
 
 type BalancePort interface {
-	AddIncome(ctx context.Context, income models.BalanceWithDesc) error
-	AddExpense(ctx context.Context, expense models.BalanceWithDesc) error
-	DoTransfer(ctx context.Context, transaction models.Transaction) error
-	GetBalance(ctx context.Context, userId int64) (models.Balance, error)
+	CreateAccount(ctx context.Context, tenant string, account models.Account) (models.Account, error)
+	GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error)
+	AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error
+	AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error
+	// AddIncomeTx and AddExpenseTx apply the checks of AddIncome and AddExpense
+	// and make the change inside tx, which the caller commits.
+	AddIncomeTx(ctx context.Context, tx Tx, tenant string, income models.BalanceWithDesc) error
+	AddExpenseTx(ctx context.Context, tx Tx, tenant string, expense models.BalanceWithDesc) error
+	DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error
+	GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error)
+	GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time, w StatementWriter) error
+	GetHistory(ctx context.Context, tenant string, userId int64, from, to time.Time) ([]models.Transaction, error)
+	SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error
+	Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error)
 }
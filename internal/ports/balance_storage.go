@@ -3,11 +3,28 @@ package ports
 import (
 	"balance/internal/domain/models"
 	"context"
+	"github.com/shopspring/decimal"
+	"time"
 )
 
 type BalanceStoragePort interface {
-	AddIncome(ctx context.Context, income models.BalanceWithDesc) error
-	AddExpense(ctx context.Context, expense models.BalanceWithDesc) error
-	DoTransfer(ctx context.Context, transaction models.Transaction) error
-	GetBalance(ctx context.Context, userId int64) (models.Balance, error)
+	CreateAccount(ctx context.Context, tenant string, account models.Account) (models.Account, error)
+	GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error)
+	AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error
+	AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error
+	// AddIncomeTx and AddExpenseTx make the change inside tx, which must come
+	// from the same storage.
+	AddIncomeTx(ctx context.Context, tx Tx, tenant string, income models.BalanceWithDesc) error
+	AddExpenseTx(ctx context.Context, tx Tx, tenant string, expense models.BalanceWithDesc) error
+	DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error
+	GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error)
+	GetBalanceAt(ctx context.Context, tenant string, userId int64, at time.Time) (decimal.Decimal, error)
+	GetHistory(ctx context.Context, tenant string, userId int64, from, to time.Time, fn func(models.Transaction) error) error
+	// GetStatement reads the balances at from and to and the history between
+	// them from one snapshot, so the closing balance always matches the rows.
+	// header gets the balances before fn gets the first row.
+	GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+		header func(opening, closing decimal.Decimal) error, fn func(models.Transaction) error) error
+	SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error
+	Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error)
 }
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type ClientStoragePort interface {
+	CreateClient(ctx context.Context, client models.Client, keyHash string) (models.Client, error)
+	GetClientByKeyHash(ctx context.Context, keyHash string) (models.Client, error)
+	ListClients(ctx context.Context, tenant string) ([]models.Client, error)
+}
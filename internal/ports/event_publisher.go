@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
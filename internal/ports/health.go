@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type HealthPort interface {
+	Ready(ctx context.Context) models.Health
+}
@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"context"
+)
+
+type HealthStoragePort interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type OutboxStoragePort interface {
+	ProcessEvents(ctx context.Context, limit int, fn func(models.Event) error) (int, error)
+	GetOutboxStats(ctx context.Context) (models.OutboxStats, error)
+}
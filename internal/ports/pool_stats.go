@@ -0,0 +1,9 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+)
+
+type PoolStatsPort interface {
+	PoolStats() models.PoolStats
+}
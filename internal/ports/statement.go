@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+)
+
+type StatementWriter interface {
+	WriteHeader(statement models.Statement) error
+	WriteEntry(entry models.StatementEntry) error
+	Close() error
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+)
+
+type TenantPort interface {
+	// Resolve returns the tenant of the request: the requested one, checked
+	// against the tenants the caller is bound to, or the default tenant.
+	Resolve(requested string, bound ...string) (string, error)
+	Settings(tenant string) (models.TenantSettings, error)
+}
@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"context"
+)
+
+type TokenVerifierPort interface {
+	// VerifyToken returns the user id and the tenant the token is issued for.
+	VerifyToken(ctx context.Context, token string) (int64, string, error)
+}
@@ -0,0 +1,5 @@
+package ports
+
+// Tx is an open transaction of a storage. Ports backed by the same storage
+// take it to make their changes part of it, the port that opened it commits.
+type Tx interface{}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+)
+
+type WebhookPort interface {
+	Subscribe(ctx context.Context, tenant string, subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, tenant string, id int64) error
+	ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error)
+	Replay(ctx context.Context, tenant string, deadLetterId int64) error
+}
@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"balance/internal/domain/models"
+	"context"
+	"time"
+)
+
+type WebhookStoragePort interface {
+	CreateSubscription(ctx context.Context, tenant string,
+		subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, tenant string, id int64) (models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, tenant string, id int64) error
+	// AddDeliveries queues the event of each delivery for its subscription. A
+	// delivery of the same event to the same subscription is queued once, so
+	// an event that the relay processes again is not sent twice.
+	AddDeliveries(ctx context.Context, tenant string, deliveries []models.WebhookDelivery) error
+	// ClaimDeliveries returns up to limit deliveries of all tenants that are
+	// due at now and postpones them until lease, so other workers skip them
+	// while they are sent.
+	ClaimDeliveries(ctx context.Context, now, lease time.Time, limit int) ([]models.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, tenant string, delivery models.WebhookDelivery) error
+	DeleteDelivery(ctx context.Context, tenant string, id int64) error
+	// DeadLetterDelivery replaces the delivery with the dead letter.
+	DeadLetterDelivery(ctx context.Context, tenant string, id int64, deadLetter models.DeadLetter) error
+	GetDeadLetter(ctx context.Context, tenant string, id int64) (models.DeadLetter, error)
+	ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error)
+	UpdateDeadLetter(ctx context.Context, tenant string, deadLetter models.DeadLetter) error
+}
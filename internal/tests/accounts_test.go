@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"balance/internal/client"
+	"balance/internal/domain/balance"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTransferRules(t *testing.T) {
+	ctx := context.Background()
+	rules, err := models.ParseTransferRules(models.DefaultTransferRules)
+	require.NoError(t, err)
+	_, err = models.ParseTransferRules("user:bank")
+	require.Error(t, err)
+	_, err = models.ParseTransferRules(" ")
+	require.Error(t, err)
+
+	c, storage := newTestClient(t)
+	service := balance.New(storage, models.Currency{Code: "RUB", Scale: 2}, zap.NewNop().Sugar())
+	service.WithTransferRules(rules)
+
+	merchant, err := c.maker.CreateAccount(ctx, 100, models.AccountMerchant, "shop-1")
+	require.NoError(t, err)
+	require.Equal(t, models.AccountMerchant, merchant.Type)
+	_, err = c.maker.CreateAccount(ctx, 200, models.AccountSystem, "fees")
+	require.NoError(t, err)
+
+	var problem *client.Error
+	_, err = c.maker.CreateAccount(ctx, 100, models.AccountUser, "")
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusConflict, problem.Status)
+	require.Equal(t, "account_exists", problem.Code)
+	_, err = c.maker.CreateAccount(ctx, 300, "bank", "")
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, "validation_failed", problem.Code)
+
+	// the user-ID API keeps creating user accounts
+	c.credit(t, 1, decimal.NewFromInt(50), "opening balance")
+	account, err := c.maker.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountUser, account.Type)
+
+	transfer := func(from, to int64) error {
+		return service.DoTransfer(ctx, models.DefaultTenant, models.Transaction{
+			UserIdFrom: from, UserIdTo: to, Value: decimal.NewFromInt(10), Time: time.Now(),
+		})
+	}
+	require.NoError(t, transfer(1, 100))
+	require.NoError(t, transfer(1, 2))
+	require.ErrorIs(t, transfer(1, 200), e.TransferNotAllowedError)
+	require.NoError(t, transfer(100, 200))
+	require.NoError(t, transfer(200, 1))
+
+	balances := map[int64]string{1: "40", 2: "10", 100: "0", 200: "0"}
+	for id, expected := range balances {
+		b, err := c.maker.GetBalance(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, expected, b.Value.String(), "balance of %d", id)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"balance/internal/adapters/memory"
+	"balance/internal/adapters/metrics"
+	"balance/internal/domain/adjustment"
+	"balance/internal/domain/auth"
+	"balance/internal/domain/balance"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdjustmentExpiry(t *testing.T) {
+	storage := memory.New()
+	logger := zap.NewNop().Sugar()
+	balanceS := balance.New(storage, models.Currency{Code: "RUB", Scale: 2}, logger)
+	service := adjustment.New(balanceS, storage, time.Millisecond, logger)
+	maker := auth.WithClient(context.Background(), models.Client{Name: "maker", Scopes: []string{models.ScopeAdmin}})
+	checker := auth.WithClient(context.Background(), models.Client{Name: "checker", Scopes: []string{models.ScopeAdmin}})
+
+	_, err := service.Propose(context.Background(), models.DefaultTenant, models.Adjustment{
+		UserId: 1, Value: decimal.NewFromInt(10), Reason: "anonymous",
+	})
+	require.ErrorIs(t, err, e.UnauthenticatedError)
+	_, err = service.Propose(auth.WithUser(maker, 1), models.DefaultTenant, models.Adjustment{
+		UserId: 1, Value: decimal.NewFromInt(10), Reason: "end user",
+	})
+	require.ErrorIs(t, err, e.ForbiddenError)
+
+	late, err := service.Propose(maker, models.DefaultTenant, models.Adjustment{UserId: 1,
+		Value: decimal.NewFromInt(10), Reason: "late"})
+	require.NoError(t, err)
+	forgotten, err := service.Propose(maker, models.DefaultTenant, models.Adjustment{UserId: 1,
+		Value: decimal.NewFromInt(10), Reason: "forgotten"})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = service.Approve(checker, models.DefaultTenant, late.Id)
+	require.ErrorIs(t, err, e.AdjustmentExpiredError)
+	late, err = service.GetAdjustment(checker, models.DefaultTenant, late.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentExpired, late.Status)
+
+	expired, err := service.Expire(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), expired)
+	forgotten, err = service.GetAdjustment(checker, models.DefaultTenant, forgotten.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentExpired, forgotten.Status)
+	require.Equal(t, models.AdjustmentExpired, forgotten.Events[len(forgotten.Events)-1].Action)
+
+	_, err = service.Approve(checker, models.DefaultTenant, forgotten.Id)
+	require.ErrorIs(t, err, e.AdjustmentNotPendingError)
+
+	_, err = storage.GetBalance(context.Background(), models.DefaultTenant, 1)
+	require.ErrorIs(t, err, e.UnknownUserIdError)
+}
+
+// failingPostStorage fails posting like a lost database connection would
+type failingPostStorage struct {
+	*memory.Storage
+	err error
+}
+
+func (s *failingPostStorage) PostAdjustment(ctx context.Context, tenant string, id int64,
+	event models.AdjustmentEvent, post func(tx ports.Tx, adjustment models.Adjustment) error) (models.Adjustment, error) {
+	if s.err != nil {
+		return models.Adjustment{}, s.err
+	}
+	return s.Storage.PostAdjustment(ctx, tenant, id, event, post)
+}
+
+func TestAdjustmentPosting(t *testing.T) {
+	storage := &failingPostStorage{Storage: memory.New(), err: errors.New("connection reset")}
+	logger := zap.NewNop().Sugar()
+	// without a tenant registry the balance service checks against its own currency
+	balanceS := balance.New(storage, models.Currency{Code: "RUB", Scale: 2}, logger)
+	m := metrics.New()
+	service := adjustment.New(m.Balance(balanceS), storage, time.Hour, logger)
+	maker := auth.WithClient(context.Background(), models.Client{Name: "maker", Scopes: []string{models.ScopeAdmin}})
+	checker := auth.WithClient(context.Background(), models.Client{Name: "checker", Scopes: []string{models.ScopeAdmin}})
+
+	proposed, err := service.Propose(maker, models.DefaultTenant, models.Adjustment{UserId: 1,
+		Value: decimal.RequireFromString("10.50"), Reason: "opening balance"})
+	require.NoError(t, err)
+
+	// a posting that may not have happened leaves the adjustment pending
+	_, err = service.Approve(checker, models.DefaultTenant, proposed.Id)
+	require.ErrorIs(t, err, e.DatabaseError)
+	pending, err := service.GetAdjustment(checker, models.DefaultTenant, proposed.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentPending, pending.Status)
+	_, err = storage.GetBalance(context.Background(), models.DefaultTenant, 1)
+	require.ErrorIs(t, err, e.UnknownUserIdError)
+
+	storage.err = nil
+	posted, err := service.Approve(checker, models.DefaultTenant, proposed.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentPosted, posted.Status)
+	require.Equal(t, "checker", posted.DecidedBy)
+	balance, err := storage.GetBalance(context.Background(), models.DefaultTenant, 1)
+	require.NoError(t, err)
+	require.Equal(t, "10.5", balance.Value.String())
+
+	// the balance checks apply at approval
+	fraction, err := service.Propose(maker, models.DefaultTenant, models.Adjustment{UserId: 1,
+		Value: decimal.RequireFromString("0.001"), Reason: "rounding"})
+	require.NoError(t, err)
+	_, err = service.Approve(checker, models.DefaultTenant, fraction.Id)
+	var invalid *e.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	fraction, err = service.GetAdjustment(checker, models.DefaultTenant, fraction.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentFailed, fraction.Status)
+	require.Equal(t, "checker", fraction.DecidedBy)
+
+	// postings go through the decorated balance service
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), `balance_operations_total{result="success",type="income"} 1`)
+	require.Contains(t, rec.Body.String(), `balance_operations_total{result="error",type="income"} 1`)
+}
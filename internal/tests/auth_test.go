@@ -0,0 +1,92 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/domain/auth"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type clientStorage struct {
+	clients map[string]models.Client
+}
+
+func (c *clientStorage) CreateClient(ctx context.Context, client models.Client,
+	keyHash string) (models.Client, error) {
+	client.Id = int64(len(c.clients) + 1)
+	c.clients[keyHash] = client
+	return client, nil
+}
+
+func (c *clientStorage) GetClientByKeyHash(ctx context.Context, keyHash string) (models.Client, error) {
+	client, ok := c.clients[keyHash]
+	if !ok {
+		return models.Client{}, e.UnknownClientError
+	}
+	return client, nil
+}
+
+func (c *clientStorage) ListClients(ctx context.Context, tenant string) ([]models.Client, error) {
+	clients := make([]models.Client, 0, len(c.clients))
+	for _, client := range c.clients {
+		if tenant == "" || client.Tenant == tenant {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	authS := auth.New(&clientStorage{clients: map[string]models.Client{}}, logger.Sugar())
+
+	_, readerKey, err := authS.CreateClient(context.Background(), "", "reader", []string{models.ScopeBalance})
+	require.NoError(t, err)
+	_, adminKey, err := authS.CreateClient(context.Background(), "", "admin", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+	_, _, err = authS.CreateClient(context.Background(), "", "bad", []string{"everything"})
+	require.ErrorIs(t, err, e.InvalidClientError)
+
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, authS, nil, httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+	handler := server.Handler()
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		apiKey string
+		status int
+	}{
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", "unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", readerKey, http.StatusOK},
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10}`, readerKey, http.StatusForbidden},
+		{http.MethodGet, "/balance/v1/webhooks", "", readerKey, http.StatusForbidden},
+		{http.MethodGet, "/admin/v1/clients", "", readerKey, http.StatusForbidden},
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10}`, adminKey, http.StatusOK},
+		{http.MethodGet, "/admin/v1/clients", "", adminKey, http.StatusOK},
+		{http.MethodPost, "/admin/v1/clients", `{"name": "billing", "scopes": ["income"]}`, adminKey,
+			http.StatusCreated},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		if c.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, c.status, rec.Code, "%s %s: %s", c.method, c.target, rec.Body.String())
+	}
+}
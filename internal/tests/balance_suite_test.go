@@ -2,16 +2,22 @@ package tests
 
 import (
 	"balance/internal/adapters/postgres"
+	"balance/internal/adapters/publisher"
 	"balance/internal/domain/balance"
 	e "balance/internal/domain/errors"
+	"balance/internal/domain/health"
 	"balance/internal/domain/models"
+	"balance/internal/domain/outbox"
 	"balance/internal/ports"
+	"balance/internal/tests/conformance"
 	"balance/internal/utils"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"go.uber.org/zap"
 	"testing"
@@ -37,6 +43,7 @@ func TestBalanceRun(t *testing.T) {
 type ApproveSuite struct {
 	suite.Suite
 	pgContainer testcontainers.Container
+	db          *postgres.Database
 	balance     ports.BalancePort
 }
 
@@ -91,13 +98,14 @@ func (suite *ApproveSuite) SetupSuite() {
 	suite.T().Log("Migrations finished")
 	suite.Require().NoError(err)
 
-	db, err := postgres.New(ctx, connString)
+	db, err := postgres.New(ctx, connString, postgres.PoolOptions{})
 
 	suite.Require().NoError(err)
 
 	logger, _ := zap.NewProduction()
-	balanceS := balance.New(db, logger.Sugar())
+	balanceS := balance.New(db, models.Currency{Code: "RUB", Scale: 2}, logger.Sugar())
 	suite.balance = balanceS
+	suite.db = db
 	suite.pgContainer = dbContainer
 
 	suite.T().Log("Suite setup is done")
@@ -119,10 +127,10 @@ func (suite *ApproveSuite) Test1Income() {
 	userId := int64(1)
 
 	income := models.BalanceWithDesc{UserId: userId, Value: incomeValue, Description: "salary"}
-	err := suite.balance.AddIncome(ctx, income)
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
 	suite.Require().NoError(err)
 
-	userBalance, err := suite.balance.GetBalance(ctx, userId)
+	userBalance, err := suite.balance.GetBalance(ctx, models.DefaultTenant, userId)
 	suite.Require().NoError(err)
 
 	userBalanceExpected := models.Balance{UserId: userId, Value: userBalance.Value}
@@ -138,16 +146,16 @@ func (suite *ApproveSuite) Test2Expense() {
 	userId := int64(2)
 
 	income := models.BalanceWithDesc{UserId: userId, Value: incomeValue, Description: "salary"}
-	err := suite.balance.AddIncome(ctx, income)
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
 	suite.Require().NoError(err)
 
 	expenseValue := decimal.NewFromFloat32(2.15)
 
 	expense := models.BalanceWithDesc{UserId: userId, Value: expenseValue, Description: "cinema"}
-	err = suite.balance.AddExpense(ctx, expense)
+	err = suite.balance.AddExpense(ctx, models.DefaultTenant, expense)
 	suite.Require().NoError(err)
 
-	userBalance, err := suite.balance.GetBalance(ctx, userId)
+	userBalance, err := suite.balance.GetBalance(ctx, models.DefaultTenant, userId)
 	suite.Require().NoError(err)
 
 	userBalanceExpected := models.Balance{UserId: userBalance.UserId, Value: incomeValue.Sub(expenseValue)}
@@ -172,16 +180,16 @@ func (suite *ApproveSuite) Test3Transfer() {
 	}
 
 	income := models.BalanceWithDesc{UserId: userIdFrom, Value: incomeValue, Description: "salary"}
-	err := suite.balance.AddIncome(ctx, income)
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
 	suite.Require().NoError(err)
 
-	err = suite.balance.DoTransfer(ctx, transfer)
+	err = suite.balance.DoTransfer(ctx, models.DefaultTenant, transfer)
 	suite.Require().NoError(err)
 
-	userBalanceFrom, err := suite.balance.GetBalance(ctx, userIdFrom)
+	userBalanceFrom, err := suite.balance.GetBalance(ctx, models.DefaultTenant, userIdFrom)
 	suite.Require().NoError(err)
 
-	userBalanceTo, err := suite.balance.GetBalance(ctx, userIdTo)
+	userBalanceTo, err := suite.balance.GetBalance(ctx, models.DefaultTenant, userIdTo)
 	suite.Require().NoError(err)
 
 	userBalanceFromExpected := models.Balance{UserId: userIdFrom, Value: incomeValue.Sub(transferValue)}
@@ -199,7 +207,7 @@ func (suite *ApproveSuite) Test4ExpenseUnknownUserId() {
 	expenseValue := decimal.NewFromFloat32(2.15)
 
 	expense := models.BalanceWithDesc{UserId: userId, Value: expenseValue, Description: "cinema"}
-	err := suite.balance.AddExpense(ctx, expense)
+	err := suite.balance.AddExpense(ctx, models.DefaultTenant, expense)
 
 	a := assert.New(suite.T())
 	a.EqualValues(errors.Is(err, e.UnknownUserIdError), true)
@@ -213,11 +221,11 @@ func (suite *ApproveSuite) Test5ExpenseNotEnoughBalance() {
 	incomeValue := decimal.NewFromFloat32(1.0)
 
 	income := models.BalanceWithDesc{UserId: userId, Value: incomeValue, Description: "salary"}
-	err := suite.balance.AddIncome(ctx, income)
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
 	suite.Require().NoError(err)
 
 	expense := models.BalanceWithDesc{UserId: userId, Value: expenseValue, Description: "cinema"}
-	err = suite.balance.AddExpense(ctx, expense)
+	err = suite.balance.AddExpense(ctx, models.DefaultTenant, expense)
 
 	a := assert.New(suite.T())
 	a.EqualValues(errors.Is(err, e.NotEnoughUserBalanceError), true)
@@ -236,7 +244,7 @@ func (suite *ApproveSuite) Test6TransferUnknownUserId() {
 		Time:        time.Now(),
 		Description: "credit",
 	}
-	err := suite.balance.DoTransfer(ctx, transfer)
+	err := suite.balance.DoTransfer(ctx, models.DefaultTenant, transfer)
 
 	a := assert.New(suite.T())
 	a.EqualValues(errors.Is(err, e.UnknownUserIdError), true)
@@ -258,10 +266,141 @@ func (suite *ApproveSuite) Test7TransferNotEnoughBalance() {
 	}
 
 	income := models.BalanceWithDesc{UserId: userIdFrom, Value: incomeValue, Description: "salary"}
-	err := suite.balance.AddIncome(ctx, income)
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
 	suite.Require().NoError(err)
 
-	err = suite.balance.DoTransfer(ctx, transfer)
+	err = suite.balance.DoTransfer(ctx, models.DefaultTenant, transfer)
 	a := assert.New(suite.T())
 	a.EqualValues(errors.Is(err, e.NotEnoughUserBalanceError), true)
 }
+
+type statementRecorder struct {
+	statement models.Statement
+	entries   []models.StatementEntry
+	closed    bool
+}
+
+func (r *statementRecorder) WriteHeader(statement models.Statement) error {
+	r.statement = statement
+	return nil
+}
+
+func (r *statementRecorder) WriteEntry(entry models.StatementEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *statementRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (suite *ApproveSuite) Test8Statement() {
+	ctx := context.Background()
+
+	userId := int64(11)
+	otherUserId := userId + 1
+	from := time.Now()
+
+	income := models.BalanceWithDesc{UserId: userId, Value: decimal.NewFromInt(10), Time: from, Description: "salary"}
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
+	suite.Require().NoError(err)
+
+	expense := models.BalanceWithDesc{UserId: userId, Value: decimal.NewFromInt(3), Time: from.Add(time.Second),
+		Description: "cinema"}
+	err = suite.balance.AddExpense(ctx, models.DefaultTenant, expense)
+	suite.Require().NoError(err)
+
+	transfer := models.Transaction{UserIdFrom: userId, UserIdTo: otherUserId, Value: decimal.NewFromInt(2),
+		Time: from.Add(2 * time.Second), Description: "credit"}
+	err = suite.balance.DoTransfer(ctx, models.DefaultTenant, transfer)
+	suite.Require().NoError(err)
+
+	recorder := &statementRecorder{}
+	err = suite.balance.GetStatement(ctx, models.DefaultTenant, userId, from, from.Add(time.Minute), recorder)
+	suite.Require().NoError(err)
+
+	a := assert.New(suite.T())
+	a.True(recorder.closed)
+	a.True(recorder.statement.OpeningBalance.Equal(decimal.Zero))
+	a.True(recorder.statement.ClosingBalance.Equal(decimal.NewFromInt(5)))
+	suite.Require().Len(recorder.entries, 3)
+	a.True(recorder.entries[0].RunningBalance.Equal(decimal.NewFromInt(10)))
+	a.True(recorder.entries[1].Amount.Equal(decimal.NewFromInt(-3)))
+	a.True(recorder.entries[2].RunningBalance.Equal(decimal.NewFromInt(5)))
+}
+
+func (suite *ApproveSuite) Test9OutboxEvents() {
+	ctx := context.Background()
+
+	userId := int64(13)
+	incomeValue := decimal.NewFromInt(7)
+
+	income := models.BalanceWithDesc{UserId: userId, Value: incomeValue, Time: time.Now(), Description: "salary"}
+	err := suite.balance.AddIncome(ctx, models.DefaultTenant, income)
+	suite.Require().NoError(err)
+
+	expense := models.BalanceWithDesc{UserId: userId, Value: decimal.NewFromInt(100), Time: time.Now(),
+		Description: "car"}
+	err = suite.balance.AddExpense(ctx, models.DefaultTenant, expense)
+	suite.Require().Error(err)
+
+	logger, _ := zap.NewProduction()
+	memory := publisher.NewMemory()
+	relay := outbox.New(suite.db, memory, logger.Sugar(), time.Second, 10)
+	for {
+		delivered, err := relay.Deliver(ctx)
+		suite.Require().NoError(err)
+		if delivered == 0 {
+			break
+		}
+	}
+
+	var userEvents []models.BalanceChangedPayload
+	for _, event := range memory.Events() {
+		if event.Type == models.TransferCompleted {
+			continue
+		}
+		var payload models.BalanceChangedPayload
+		suite.Require().NoError(json.Unmarshal(event.Payload, &payload))
+		if payload.UserId == userId {
+			suite.Require().Equal(models.BalanceCredited, event.Type)
+			userEvents = append(userEvents, payload)
+		}
+	}
+
+	suite.Require().Len(userEvents, 1)
+	suite.Require().True(userEvents[0].Value.Equal(incomeValue))
+
+	delivered, err := relay.Deliver(ctx)
+	suite.Require().NoError(err)
+	suite.Require().Zero(delivered)
+}
+
+func (suite *ApproveSuite) Test10Readiness() {
+	ctx := context.Background()
+
+	expected, err := utils.LatestMigrationVersion("../../db/changelog/")
+	suite.Require().NoError(err)
+
+	healthS := health.New(time.Second)
+	healthS.Register("postgres", health.DatabaseCheck(suite.db))
+	healthS.Register("migrations", health.MigrationCheck(suite.db, expected))
+	suite.Require().Equal(models.HealthOk, healthS.Ready(ctx).Status)
+
+	healthS.Register("future_migrations", health.MigrationCheck(suite.db, expected+1))
+	report := healthS.Ready(ctx)
+	suite.Require().Equal(models.HealthFail, report.Status)
+	suite.Require().Equal(models.HealthFail, report.Components["future_migrations"].Status)
+}
+
+// TestStorageConformance runs after the numbered tests and empties the tables
+// before every conformance subtest.
+func (suite *ApproveSuite) TestStorageConformance() {
+	conformance.BalanceStorage(suite.T(), func(t *testing.T) ports.BalanceStoragePort {
+		_, err := suite.db.DB.Exec(context.Background(),
+			"TRUNCATE balance.balance, balance.history, balance.outbox RESTART IDENTITY")
+		require.NoError(t, err)
+		return suite.db
+	})
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"balance/internal/adapters/statement"
+	"balance/internal/domain/models"
+	"bytes"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCamt053MatchesSchema(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	w := statement.NewCamt053(buf)
+
+	err = w.WriteHeader(models.Statement{
+		UserId:         1,
+		Currency:       models.Currency{Code: "RUB", Scale: 2},
+		From:           from,
+		To:             from.AddDate(0, 1, 0),
+		OpeningBalance: decimal.NewFromInt(100),
+		ClosingBalance: decimal.RequireFromString("107.40"),
+	})
+	require.NoError(t, err)
+
+	entries := []models.StatementEntry{
+		{
+			Transaction: models.Transaction{Id: 1, UserIdFrom: 0, UserIdTo: 1, Value: decimal.RequireFromString("10.55"),
+				Time: from.Add(time.Hour), Description: "salary <october> & bonus"},
+			Amount:         decimal.RequireFromString("10.55"),
+			RunningBalance: decimal.RequireFromString("110.55"),
+		},
+		{
+			Transaction: models.Transaction{Id: 2, UserIdFrom: 1, UserIdTo: 2, Value: decimal.RequireFromString("3.15"),
+				Time: from.Add(2 * time.Hour)},
+			Amount:         decimal.RequireFromString("-3.15"),
+			RunningBalance: decimal.RequireFromString("107.40"),
+		},
+	}
+	for _, entry := range entries {
+		require.NoError(t, w.WriteEntry(entry))
+	}
+	require.NoError(t, w.Close())
+
+	document := filepath.Join(t.TempDir(), "statement.xml")
+	require.NoError(t, os.WriteFile(document, buf.Bytes(), 0o600))
+
+	out, err := exec.Command(xmllint, "--noout", "--schema", "testdata/camt.053.001.08.xsd", document).CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.Contains(t, buf.String(), "<CdtDbtInd>DBIT</CdtDbtInd>")
+}
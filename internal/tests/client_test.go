@@ -0,0 +1,190 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/memory"
+	"balance/internal/client"
+	"balance/internal/domain/adjustment"
+	"balance/internal/domain/auth"
+	"balance/internal/domain/balance"
+	"balance/internal/domain/models"
+	"bytes"
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOperators struct {
+	maker, checker *client.Client
+}
+
+// newTestClient starts the HTTP API over in-memory storage with two admin
+// operators, so adjustments can be proposed by one and approved by the other.
+func newTestClient(t *testing.T) (testOperators, *memory.Storage) {
+	storage := memory.New()
+	logger := zap.NewNop().Sugar()
+	balanceS := balance.New(storage, models.Currency{Code: "RUB", Scale: 2}, logger)
+	authS := auth.New(&clientStorage{clients: map[string]models.Client{}}, logger)
+
+	_, makerKey, err := authS.CreateClient(context.Background(), "", "maker", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+	_, checkerKey, err := authS.CreateClient(context.Background(), "", "checker", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+
+	server, err := httpadapter.New(balanceS, nil, authS, nil, httpadapter.Limits{}, logger)
+	require.NoError(t, err)
+	server.WithAdjustments(adjustment.New(balanceS, storage, time.Hour, logger))
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return testOperators{
+		maker:   client.New(client.Options{BaseURL: ts.URL, ApiKey: makerKey}),
+		checker: client.New(client.Options{BaseURL: ts.URL, ApiKey: checkerKey}),
+	}, storage
+}
+
+// credit posts an approved adjustment
+func (o testOperators) credit(t *testing.T, userId int64, value decimal.Decimal, reason string) models.Adjustment {
+	proposed, err := o.maker.Propose(context.Background(), userId, value, reason, "")
+	require.NoError(t, err)
+	posted, err := o.checker.Approve(context.Background(), proposed.Id)
+	require.NoError(t, err)
+	return posted
+}
+
+func TestClientAdjustments(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+
+	proposed, err := c.maker.Propose(ctx, 1, decimal.RequireFromString("100.50"), "opening balance",
+		"https://tickets.example/OPS-1")
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentPending, proposed.Status)
+	require.Equal(t, "maker", proposed.ProposedBy)
+
+	// nothing is posted until another operator approves
+	_, err = c.maker.GetBalance(ctx, 1)
+	var problem *client.Error
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusNotFound, problem.Status)
+
+	_, err = c.maker.Approve(ctx, proposed.Id)
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusForbidden, problem.Status)
+	require.Equal(t, "self_approval", problem.Code)
+
+	posted, err := c.checker.Approve(ctx, proposed.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentPosted, posted.Status)
+	require.Equal(t, "checker", posted.DecidedBy)
+
+	_, err = c.checker.Approve(ctx, proposed.Id)
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusConflict, problem.Status)
+	require.Equal(t, "adjustment_not_pending", problem.Code)
+
+	c.credit(t, 1, decimal.RequireFromString("-0.50"), "fee refund reversal")
+
+	balance, err := c.maker.GetBalance(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "100", balance.Value.String())
+
+	adjustments, err := c.maker.ListAdjustments(ctx, 1, models.AdjustmentPosted)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 2)
+
+	audit, err := c.maker.GetAdjustment(ctx, proposed.Id)
+	require.NoError(t, err)
+	require.Equal(t, "https://tickets.example/OPS-1", audit.AttachmentRef)
+	var actions []string
+	for _, event := range audit.Events {
+		actions = append(actions, event.Action+" by "+event.Client)
+	}
+	require.Equal(t, []string{"proposed by maker", "approved by checker", "posted by checker"}, actions)
+
+	history, err := c.maker.GetHistory(ctx, 1, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "adjustment: opening balance", history[0].Description)
+
+	_, err = c.maker.Propose(ctx, 1, decimal.NewFromInt(1), " ", "")
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusBadRequest, problem.Status)
+	require.Equal(t, "validation_failed", problem.Code)
+
+	// a debit that the balance cannot cover fails at approval and stays failed
+	overdraft, err := c.maker.Propose(ctx, 1, decimal.NewFromInt(-1000), "too much", "")
+	require.NoError(t, err)
+	_, err = c.checker.Approve(ctx, overdraft.Id)
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, "insufficient_balance", problem.Code)
+	overdraft, err = c.maker.GetAdjustment(ctx, overdraft.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentFailed, overdraft.Status)
+
+	withdrawn, err := c.maker.Propose(ctx, 1, decimal.NewFromInt(5), "duplicate", "")
+	require.NoError(t, err)
+	withdrawn, err = c.maker.Reject(ctx, withdrawn.Id, "proposed twice")
+	require.NoError(t, err)
+	require.Equal(t, models.AdjustmentRejected, withdrawn.Status)
+	_, err = c.checker.Approve(ctx, withdrawn.Id)
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, "adjustment_not_pending", problem.Code)
+
+	pending, err := c.checker.ListAdjustments(ctx, 0, models.AdjustmentPending)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestClientFreeze(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+
+	c.credit(t, 1, decimal.NewFromInt(10), "opening balance")
+
+	require.NoError(t, c.maker.Freeze(ctx, 1))
+	balance, err := c.maker.GetBalance(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, balance.Frozen)
+
+	blocked, err := c.maker.Propose(ctx, 1, decimal.NewFromInt(-1), "blocked", "")
+	require.NoError(t, err)
+	_, err = c.checker.Approve(ctx, blocked.Id)
+	var problem *client.Error
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusConflict, problem.Status)
+	require.Equal(t, "account_frozen", problem.Code)
+
+	require.NoError(t, c.maker.Unfreeze(ctx, 1))
+	c.credit(t, 1, decimal.NewFromInt(-1), "allowed")
+
+	err = c.maker.Freeze(ctx, 2)
+	require.ErrorAs(t, err, &problem)
+	require.Equal(t, http.StatusNotFound, problem.Status)
+}
+
+func TestClientReconcileAndStatement(t *testing.T) {
+	ctx := context.Background()
+	c, storage := newTestClient(t)
+
+	c.credit(t, 1, decimal.NewFromInt(10), "opening balance")
+	require.NoError(t, storage.DoTransfer(ctx, models.DefaultTenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: decimal.NewFromInt(4), Time: time.Now(), Description: "gift",
+	}))
+
+	reconciliation, err := c.maker.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reconciliation.Accounts)
+	require.Empty(t, reconciliation.Mismatches)
+
+	var statement bytes.Buffer
+	err = c.maker.Statement(ctx, 1, time.Time{}, time.Time{}, "csv", &statement)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(statement.String(), "gift"), statement.String())
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"balance/internal/config"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("HTTP_PORT", "3000")
+	t.Setenv("POSTGRES_USER", "app")
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_DB", "app")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestConfigDefaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := config.NewConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "3001", cfg.GrpcPort)
+	require.Equal(t, int32(10), cfg.PostgresMaxConns)
+	require.Equal(t, 30*time.Second, cfg.RequestTimeout)
+	require.Equal(t, "info", cfg.LogLevel)
+	require.Equal(t, "json", cfg.LogFormat)
+}
+
+func TestConfigLayers(t *testing.T) {
+	setRequiredEnv(t)
+	file := writeFile(t, "balance.yaml", `
+http_port: "4000"
+postgres_max_conns: 20
+postgres_min_conns: 2
+request_timeout: 5s
+log_level: debug
+rate_limit_routes:
+  /balance/v1/transfer: 5
+  /balance/v1/statements: 0
+`)
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("POSTGRES_MAX_CONNS", "30")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := config.NewConfig([]string{"-log-level", "error", "-auth-enabled"})
+	require.NoError(t, err)
+
+	// the environment overrides the file
+	require.Equal(t, "3000", cfg.HttpPort)
+	require.Equal(t, int32(30), cfg.PostgresMaxConns)
+	// the file overrides defaults
+	require.Equal(t, int32(2), cfg.PostgresMinConns)
+	require.Equal(t, 5*time.Second, cfg.RequestTimeout)
+	require.Equal(t, map[string]float64{"/balance/v1/transfer": 5, "/balance/v1/statements": 0}, cfg.RateLimitRoutes)
+	// flags override the environment
+	require.Equal(t, "error", cfg.LogLevel)
+	require.True(t, cfg.AuthEnabled)
+}
+
+func TestConfigSecretFile(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("POSTGRES_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+
+	cfg, err := config.NewConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", cfg.PostgresPassword)
+
+	t.Setenv("POSTGRES_PASSWORD", "other")
+	_, err = config.NewConfig(nil)
+	require.ErrorContains(t, err, "both POSTGRES_PASSWORD and POSTGRES_PASSWORD_FILE are set")
+}
+
+func TestConfigReportsAllErrors(t *testing.T) {
+	t.Setenv("POSTGRES_MAX_CONNS", "many")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("TRACING_EXPORTER", "otlp")
+	t.Setenv("RATE_LIMIT_ROUTES", "/balance/v1/transfer:-1")
+	t.Setenv("TRANSFER_RULES", "user:bank, ")
+	t.Setenv("CURRENCY", "XXX")
+	t.Setenv("CONFIG_FILE", writeFile(t, "balance.yaml", `
+tenants:
+  acme:
+    currency: ZZZ
+`))
+
+	_, err := config.NewConfig([]string{"-postgres-min-conns", "-1"})
+	require.Error(t, err)
+
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.ElementsMatch(t, config.Errors{
+		`postgres_max_conns: invalid POSTGRES_MAX_CONNS value: strconv.ParseInt: parsing "many": invalid syntax`,
+		"http_port: is required",
+		"postgres_user: is required",
+		"postgres_host: is required",
+		"postgres_db: is required",
+		"postgres_min_conns: must be between 0 and postgres_max_conns, got -1",
+		`log_format: must be json or console, got "xml"`,
+		"tracing_endpoint: is required for the otlp exporter",
+		"rate_limit_routes: rate for /balance/v1/transfer must not be negative, got -1",
+		`transfer_rules: unknown account type "bank" in "user:bank"`,
+		`currency: unsupported currency "XXX"`,
+		`tenants: currency of acme: unsupported currency "ZZZ"`,
+	}, errs)
+}
+
+func TestConfigUnknownFileKey(t *testing.T) {
+	setRequiredEnv(t)
+
+	_, err := config.NewConfig([]string{"-config", writeFile(t, "balance.yaml", "postgres_max_con: 5\n")})
+	require.ErrorContains(t, err, "field postgres_max_con not found")
+}
+
+func TestConfigEmptyTransferRules(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("TRANSFER_RULES", " , ")
+
+	_, err := config.NewConfig(nil)
+	require.ErrorContains(t, err, "transfer_rules: must list at least one from:to pair")
+}
@@ -0,0 +1,582 @@
+// Package conformance verifies that a storage adapter honours the contract of
+// its port, so every implementation behaves the same behind balance.Service.
+package conformance
+
+import (
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns an empty storage. It is called once per subtest, so
+// subtests neither share state nor depend on their order.
+type Factory func(t *testing.T) ports.BalanceStoragePort
+
+// BalanceStorage runs the ports.BalanceStoragePort contract against the
+// storage returned by newStorage.
+func BalanceStorage(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, storage ports.BalanceStoragePort)
+	}{
+		{"Income", testIncome},
+		{"Expense", testExpense},
+		{"Transfer", testTransfer},
+		{"UnknownUser", testUnknownUser},
+		{"NotEnoughBalance", testNotEnoughBalance},
+		{"FailedTransferIsAtomic", testFailedTransferIsAtomic},
+		{"History", testHistory},
+		{"BalanceAt", testBalanceAt},
+		{"HistoryCallbackError", testHistoryCallbackError},
+		{"Statement", testStatement},
+		{"ConcurrentTransfers", testConcurrentTransfers},
+		{"ConcurrentOverdraft", testConcurrentOverdraft},
+		{"DecimalPrecision", testDecimalPrecision},
+		{"Freeze", testFreeze},
+		{"Reconcile", testReconcile},
+		{"TenantIsolation", testTenantIsolation},
+		{"Accounts", testAccounts},
+		{"TransferRules", testTransferRules},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStorage(t))
+		})
+	}
+}
+
+var start = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// tenant is where every subtest but TenantIsolation keeps its accounts
+const tenant = models.DefaultTenant
+
+func amount(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func income(t *testing.T, storage ports.BalanceStoragePort, userId int64, value string, at time.Time) {
+	err := storage.AddIncome(context.Background(), tenant, models.BalanceWithDesc{
+		UserId: userId, Value: amount(value), Time: at, Description: "income",
+	})
+	require.NoError(t, err)
+}
+
+func requireBalance(t *testing.T, storage ports.BalanceStoragePort, userId int64, expected string) {
+	balance, err := storage.GetBalance(context.Background(), tenant, userId)
+	require.NoError(t, err)
+	require.Equal(t, userId, balance.UserId)
+	require.Truef(t, balance.Value.Equal(amount(expected)),
+		"balance of user %d: expected %s, got %s", userId, expected, balance.Value)
+}
+
+func history(t *testing.T, storage ports.BalanceStoragePort, userId int64, from, to time.Time) []models.Transaction {
+	var transactions []models.Transaction
+	err := storage.GetHistory(context.Background(), tenant, userId, from, to, func(transaction models.Transaction) error {
+		transactions = append(transactions, transaction)
+		return nil
+	})
+	require.NoError(t, err)
+	return transactions
+}
+
+func fullHistory(t *testing.T, storage ports.BalanceStoragePort, userId int64) []models.Transaction {
+	return history(t, storage, userId, start.Add(-24*time.Hour), start.Add(24*time.Hour))
+}
+
+func testIncome(t *testing.T, storage ports.BalanceStoragePort) {
+	income(t, storage, 1, "10.55", start)
+	income(t, storage, 1, "4.45", start.Add(time.Minute))
+
+	requireBalance(t, storage, 1, "15")
+}
+
+func testExpense(t *testing.T, storage ports.BalanceStoragePort) {
+	income(t, storage, 1, "10.55", start)
+
+	err := storage.AddExpense(context.Background(), tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("5.15"), Time: start.Add(time.Minute), Description: "cinema",
+	})
+	require.NoError(t, err)
+	requireBalance(t, storage, 1, "5.40")
+
+	err = storage.AddExpense(context.Background(), tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("5.40"), Time: start.Add(2 * time.Minute),
+	})
+	require.NoError(t, err)
+	requireBalance(t, storage, 1, "0")
+}
+
+func testTransfer(t *testing.T, storage ports.BalanceStoragePort) {
+	income(t, storage, 1, "100", start)
+	income(t, storage, 2, "1", start)
+
+	err := storage.DoTransfer(context.Background(), tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: amount("30.5"), Time: start.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	requireBalance(t, storage, 1, "69.5")
+	requireBalance(t, storage, 2, "31.5")
+
+	// a transfer creates the receiving account
+	err = storage.DoTransfer(context.Background(), tenant, models.Transaction{
+		UserIdFrom: 2, UserIdTo: 3, Value: amount("1.5"), Time: start.Add(2 * time.Minute),
+	})
+	require.NoError(t, err)
+	requireBalance(t, storage, 2, "30")
+	requireBalance(t, storage, 3, "1.5")
+}
+
+func testUnknownUser(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+
+	_, err := storage.GetBalance(ctx, tenant, 1)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "get balance: %v", err)
+
+	err = storage.AddExpense(ctx, tenant, models.BalanceWithDesc{UserId: 1, Value: amount("1"), Time: start})
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "expense: %v", err)
+
+	err = storage.DoTransfer(ctx, tenant, models.Transaction{UserIdFrom: 1, UserIdTo: 2, Value: amount("1"),
+		Time: start})
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "transfer: %v", err)
+
+	_, err = storage.GetBalance(ctx, tenant, 2)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "transfer target: %v", err)
+	require.Empty(t, fullHistory(t, storage, 1))
+}
+
+func testNotEnoughBalance(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	income(t, storage, 1, "10", start)
+
+	err := storage.AddExpense(ctx, tenant, models.BalanceWithDesc{UserId: 1, Value: amount("10.01"), Time: start})
+	require.True(t, errors.Is(err, e.NotEnoughUserBalanceError), "expense: %v", err)
+
+	err = storage.DoTransfer(ctx, tenant, models.Transaction{UserIdFrom: 1, UserIdTo: 2, Value: amount("10.01"),
+		Time: start})
+	require.True(t, errors.Is(err, e.NotEnoughUserBalanceError), "transfer: %v", err)
+
+	requireBalance(t, storage, 1, "10")
+	require.Len(t, fullHistory(t, storage, 1), 1)
+}
+
+func testFailedTransferIsAtomic(t *testing.T, storage ports.BalanceStoragePort) {
+	income(t, storage, 1, "5", start)
+	income(t, storage, 2, "7", start)
+
+	err := storage.DoTransfer(context.Background(), tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: amount("6"), Time: start.Add(time.Minute),
+	})
+	require.Error(t, err)
+
+	// neither side changed and nothing was recorded
+	requireBalance(t, storage, 1, "5")
+	requireBalance(t, storage, 2, "7")
+	require.Len(t, fullHistory(t, storage, 1), 1)
+	require.Len(t, fullHistory(t, storage, 2), 1)
+
+	_, err = storage.GetBalance(context.Background(), tenant, 3)
+	require.Error(t, err)
+	err = storage.DoTransfer(context.Background(), tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 3, Value: amount("6"), Time: start.Add(time.Minute),
+	})
+	require.Error(t, err)
+	_, err = storage.GetBalance(context.Background(), tenant, 3)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "failed transfer created the target: %v", err)
+}
+
+func testHistory(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+
+	require.NoError(t, storage.AddIncome(ctx, tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("100"), Time: start, Description: "salary", Client: "billing",
+	}))
+	require.NoError(t, storage.AddExpense(ctx, tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("20"), Time: start.Add(time.Hour), Description: "cinema",
+	}))
+	require.NoError(t, storage.DoTransfer(ctx, tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: amount("30"), Time: start.Add(2 * time.Hour), Description: "gift",
+	}))
+
+	transactions := fullHistory(t, storage, 1)
+	require.Len(t, transactions, 3)
+
+	expected := []models.Transaction{
+		{UserIdFrom: 0, UserIdTo: 1, Value: amount("100"), Time: start, Description: "salary", Client: "billing"},
+		{UserIdFrom: 1, UserIdTo: 0, Value: amount("20"), Time: start.Add(time.Hour), Description: "cinema"},
+		{UserIdFrom: 1, UserIdTo: 2, Value: amount("30"), Time: start.Add(2 * time.Hour), Description: "gift"},
+	}
+	ids := map[int64]bool{}
+	for i, transaction := range transactions {
+		require.Positive(t, transaction.Id)
+		require.False(t, ids[transaction.Id], "duplicate history id %d", transaction.Id)
+		ids[transaction.Id] = true
+
+		require.Equal(t, expected[i].UserIdFrom, transaction.UserIdFrom)
+		require.Equal(t, expected[i].UserIdTo, transaction.UserIdTo)
+		require.True(t, expected[i].Value.Equal(transaction.Value), "value %s", transaction.Value)
+		require.True(t, expected[i].Time.Equal(transaction.Time), "time %s", transaction.Time)
+		require.Equal(t, expected[i].Description, transaction.Description)
+		require.Equal(t, expected[i].Client, transaction.Client)
+	}
+
+	// the receiving side sees the transfer too
+	received := fullHistory(t, storage, 2)
+	require.Len(t, received, 1)
+	require.Equal(t, transactions[2].Id, received[0].Id)
+
+	// the range is half-open
+	window := history(t, storage, 1, start.Add(time.Hour), start.Add(2*time.Hour))
+	require.Len(t, window, 1)
+	require.Equal(t, "cinema", window[0].Description)
+}
+
+func testBalanceAt(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	income(t, storage, 1, "100", start)
+	require.NoError(t, storage.AddExpense(ctx, tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("40"), Time: start.Add(time.Hour),
+	}))
+
+	for _, c := range []struct {
+		at       time.Time
+		expected string
+	}{
+		{start, "0"},
+		{start.Add(time.Minute), "100"},
+		{start.Add(time.Hour), "100"},
+		{start.Add(time.Hour + time.Minute), "60"},
+	} {
+		balance, err := storage.GetBalanceAt(ctx, tenant, 1, c.at)
+		require.NoError(t, err)
+		require.True(t, balance.Equal(amount(c.expected)), "balance at %s: %s", c.at, balance)
+	}
+
+	balance, err := storage.GetBalanceAt(ctx, tenant, 42, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, balance.IsZero())
+}
+
+func testHistoryCallbackError(t *testing.T, storage ports.BalanceStoragePort) {
+	income(t, storage, 1, "1", start)
+	income(t, storage, 1, "2", start.Add(time.Minute))
+
+	stop := errors.New("stop")
+	calls := 0
+	end := start.Add(time.Hour)
+	err := storage.GetHistory(context.Background(), tenant, 1, start, end, func(models.Transaction) error {
+		calls++
+		return stop
+	})
+	require.True(t, errors.Is(err, stop), "callback error is returned: %v", err)
+	require.Equal(t, 1, calls)
+}
+
+func testStatement(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	income(t, storage, 1, "100", start)
+	require.NoError(t, storage.AddExpense(ctx, tenant, models.BalanceWithDesc{
+		UserId: 1, Value: amount("40"), Time: start.Add(time.Hour),
+	}))
+
+	var opening, closing decimal.Decimal
+	var rows []models.Transaction
+	header := func(o, c decimal.Decimal) error {
+		opening, closing = o, c
+		// a write during the statement is not part of it
+		income(t, storage, 1, "5", start.Add(2*time.Hour))
+		return nil
+	}
+	err := storage.GetStatement(ctx, tenant, 1, start.Add(time.Minute), start.Add(3*time.Hour), header,
+		func(transaction models.Transaction) error {
+			rows = append(rows, transaction)
+			return nil
+		})
+	require.NoError(t, err)
+
+	require.True(t, opening.Equal(amount("100")), "opening balance: %s", opening)
+	require.True(t, closing.Equal(amount("60")), "closing balance: %s", closing)
+	require.Len(t, rows, 1)
+	require.True(t, rows[0].Value.Equal(amount("40")))
+	requireBalance(t, storage, 1, "65")
+}
+
+func testConcurrentTransfers(t *testing.T, storage ports.BalanceStoragePort) {
+	const users = 4
+	const perUser = 20
+	for userId := int64(1); userId <= users; userId++ {
+		income(t, storage, userId, "100", start)
+	}
+
+	var wg sync.WaitGroup
+	for userId := int64(1); userId <= users; userId++ {
+		for i := 0; i < perUser; i++ {
+			wg.Add(1)
+			go func(from int64, i int) {
+				defer wg.Done()
+				to := from%users + 1
+				if i%2 == 1 {
+					to = (from+1)%users + 1
+				}
+				err := storage.DoTransfer(context.Background(), tenant, models.Transaction{
+					UserIdFrom: from, UserIdTo: to, Value: amount("1.25"), Time: start.Add(time.Minute),
+				})
+				assert.NoError(t, err)
+			}(userId, i)
+		}
+	}
+	wg.Wait()
+
+	total := decimal.Zero
+	for userId := int64(1); userId <= users; userId++ {
+		balance, err := storage.GetBalance(context.Background(), tenant, userId)
+		require.NoError(t, err)
+		require.False(t, balance.Value.IsNegative())
+		total = total.Add(balance.Value)
+	}
+	require.True(t, total.Equal(amount("400")), "money is conserved, total %s", total)
+}
+
+func testConcurrentOverdraft(t *testing.T, storage ports.BalanceStoragePort) {
+	const attempts = 30
+	income(t, storage, 1, "10", start)
+	income(t, storage, 2, "0", start)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := storage.DoTransfer(context.Background(), tenant, models.Transaction{
+				UserIdFrom: 1, UserIdTo: 2, Value: amount("1"), Time: start.Add(time.Minute),
+			})
+			if err != nil {
+				assert.True(t, errors.Is(err, e.NotEnoughUserBalanceError), "transfer: %v", err)
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 10, succeeded)
+	requireBalance(t, storage, 1, "0")
+	requireBalance(t, storage, 2, "10")
+	require.Len(t, fullHistory(t, storage, 1), 11)
+}
+
+func testDecimalPrecision(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+
+	// 0.1 + 0.2 is exact
+	income(t, storage, 1, "0.1", start)
+	income(t, storage, 1, "0.2", start)
+	requireBalance(t, storage, 1, "0.3")
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, storage.DoTransfer(ctx, tenant, models.Transaction{
+			UserIdFrom: 1, UserIdTo: 2, Value: amount("0.003"), Time: start.Add(time.Minute),
+		}))
+	}
+	requireBalance(t, storage, 1, "0")
+	requireBalance(t, storage, 2, "0.3")
+
+	income(t, storage, 3, "12345678901234567890.123456789", start)
+	require.NoError(t, storage.AddExpense(ctx, tenant, models.BalanceWithDesc{
+		UserId: 3, Value: amount("0.000000001"), Time: start.Add(time.Minute),
+	}))
+	requireBalance(t, storage, 3, "12345678901234567890.123456788")
+
+	at, err := storage.GetBalanceAt(ctx, tenant, 3, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, at.Equal(amount("12345678901234567890.123456788")), "balance at: %s", at)
+}
+
+func testFreeze(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	income(t, storage, 1, "10", start)
+	income(t, storage, 2, "10", start)
+
+	err := storage.SetFrozen(ctx, tenant, 3, true)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "freeze unknown: %v", err)
+
+	require.NoError(t, storage.SetFrozen(ctx, tenant, 1, true))
+	balance, err := storage.GetBalance(ctx, tenant, 1)
+	require.NoError(t, err)
+	require.True(t, balance.Frozen)
+
+	err = storage.AddIncome(ctx, tenant, models.BalanceWithDesc{UserId: 1, Value: amount("1"), Time: start})
+	require.True(t, errors.Is(err, e.AccountFrozenError), "income: %v", err)
+	err = storage.AddExpense(ctx, tenant, models.BalanceWithDesc{UserId: 1, Value: amount("1"), Time: start})
+	require.True(t, errors.Is(err, e.AccountFrozenError), "expense: %v", err)
+	err = storage.DoTransfer(ctx, tenant, models.Transaction{UserIdFrom: 1, UserIdTo: 2, Value: amount("1"),
+		Time: start})
+	require.True(t, errors.Is(err, e.AccountFrozenError), "transfer from: %v", err)
+	err = storage.DoTransfer(ctx, tenant, models.Transaction{UserIdFrom: 2, UserIdTo: 1, Value: amount("1"),
+		Time: start})
+	require.True(t, errors.Is(err, e.AccountFrozenError), "transfer to: %v", err)
+
+	requireBalance(t, storage, 1, "10")
+	requireBalance(t, storage, 2, "10")
+	require.Len(t, fullHistory(t, storage, 1), 1)
+
+	require.NoError(t, storage.SetFrozen(ctx, tenant, 1, false))
+	balance, err = storage.GetBalance(ctx, tenant, 1)
+	require.NoError(t, err)
+	require.False(t, balance.Frozen)
+	require.NoError(t, storage.DoTransfer(ctx, tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: amount("1"), Time: start,
+	}))
+	requireBalance(t, storage, 1, "9")
+}
+
+func testReconcile(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+
+	reconciliation, err := storage.Reconcile(ctx, tenant)
+	require.NoError(t, err)
+	require.Zero(t, reconciliation.Accounts)
+	require.Empty(t, reconciliation.Mismatches)
+
+	income(t, storage, 1, "10.5", start)
+	require.NoError(t, storage.DoTransfer(ctx, tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: amount("3.25"), Time: start,
+	}))
+	require.NoError(t, storage.AddExpense(ctx, tenant, models.BalanceWithDesc{UserId: 2, Value: amount("1"),
+		Time: start}))
+
+	reconciliation, err = storage.Reconcile(ctx, tenant)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reconciliation.Accounts)
+	require.Empty(t, reconciliation.Mismatches)
+}
+
+func testTenantIsolation(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	const other = "acme"
+
+	income(t, storage, 1, "10", start)
+	require.NoError(t, storage.AddIncome(ctx, other, models.BalanceWithDesc{
+		UserId: 1, Value: amount("3"), Time: start, Description: "other tenant",
+	}))
+
+	// the same user id is a separate account in every tenant
+	requireBalance(t, storage, 1, "10")
+	balance, err := storage.GetBalance(ctx, other, 1)
+	require.NoError(t, err)
+	require.True(t, balance.Value.Equal(amount("3")), "other tenant balance %s", balance.Value)
+
+	err = storage.AddExpense(ctx, other, models.BalanceWithDesc{UserId: 1, Value: amount("5"), Time: start})
+	require.True(t, errors.Is(err, e.NotEnoughUserBalanceError), "expense: %v", err)
+
+	_, err = storage.GetBalance(ctx, "unknown", 1)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "unknown tenant: %v", err)
+
+	require.NoError(t, storage.SetFrozen(ctx, other, 1, true))
+	balance, err = storage.GetBalance(ctx, tenant, 1)
+	require.NoError(t, err)
+	require.False(t, balance.Frozen, "freeze leaked into another tenant")
+
+	transactions := fullHistory(t, storage, 1)
+	require.Len(t, transactions, 1)
+	require.Equal(t, "income", transactions[0].Description)
+
+	reconciliation, err := storage.Reconcile(ctx, other)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), reconciliation.Accounts)
+	require.Empty(t, reconciliation.Mismatches)
+}
+
+func testAccounts(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+
+	merchant, err := storage.CreateAccount(ctx, tenant, models.Account{
+		Id: 10, Type: models.AccountMerchant, OwnerRef: "shop-1", CreatedAt: start,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), merchant.Id)
+
+	_, err = storage.CreateAccount(ctx, tenant, models.Account{Id: 10, Type: models.AccountSystem, CreatedAt: start})
+	require.True(t, errors.Is(err, e.AccountExistsError), "duplicate: %v", err)
+
+	account, err := storage.GetAccount(ctx, tenant, 10)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountMerchant, account.Type)
+	require.Equal(t, "shop-1", account.OwnerRef)
+	require.True(t, account.CreatedAt.Equal(start), "created at %s", account.CreatedAt)
+
+	// a created account has an empty balance and takes part in the balance API
+	requireBalance(t, storage, 10, "0")
+	income(t, storage, 1, "5", start)
+	require.NoError(t, storage.DoTransfer(ctx, tenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 10, Value: amount("2"), Time: start,
+	}))
+	requireBalance(t, storage, 10, "2")
+	account, err = storage.GetAccount(ctx, tenant, 10)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountMerchant, account.Type)
+
+	// accounts created implicitly by the balance API are user accounts
+	account, err = storage.GetAccount(ctx, tenant, 1)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountUser, account.Type)
+	require.Empty(t, account.OwnerRef)
+	require.False(t, account.CreatedAt.IsZero())
+
+	_, err = storage.CreateAccount(ctx, tenant, models.Account{Id: 1, Type: models.AccountMerchant, CreatedAt: start})
+	require.True(t, errors.Is(err, e.AccountExistsError), "existing balance: %v", err)
+
+	_, err = storage.GetAccount(ctx, tenant, 2)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "unknown: %v", err)
+	_, err = storage.GetAccount(ctx, "other", 10)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "other tenant: %v", err)
+
+	reconciliation, err := storage.Reconcile(ctx, tenant)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reconciliation.Accounts)
+	require.Empty(t, reconciliation.Mismatches)
+}
+
+func testTransferRules(t *testing.T, storage ports.BalanceStoragePort) {
+	ctx := context.Background()
+	rules, err := models.ParseTransferRules("user:user,user:merchant")
+	require.NoError(t, err)
+
+	_, err = storage.CreateAccount(ctx, tenant, models.Account{Id: 10, Type: models.AccountMerchant, CreatedAt: start})
+	require.NoError(t, err)
+	_, err = storage.CreateAccount(ctx, tenant, models.Account{Id: 20, Type: models.AccountSystem, CreatedAt: start})
+	require.NoError(t, err)
+	income(t, storage, 1, "10", start)
+
+	transfer := func(from, to int64) error {
+		return storage.DoTransfer(ctx, tenant, models.Transaction{
+			UserIdFrom: from, UserIdTo: to, Value: amount("1"), Time: start, Rules: rules,
+		})
+	}
+	require.NoError(t, transfer(1, 10))
+	// a missing receiving account is checked as a user account
+	require.NoError(t, transfer(1, 2))
+	err = transfer(1, 20)
+	require.True(t, errors.Is(err, e.TransferNotAllowedError), "user to system: %v", err)
+	err = transfer(10, 1)
+	require.True(t, errors.Is(err, e.TransferNotAllowedError), "merchant to user: %v", err)
+	err = transfer(3, 1)
+	require.True(t, errors.Is(err, e.UnknownUserIdError), "unknown sender: %v", err)
+
+	requireBalance(t, storage, 1, "8")
+	requireBalance(t, storage, 10, "1")
+	requireBalance(t, storage, 20, "0")
+	require.Len(t, fullHistory(t, storage, 1), 3)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	grpcadapter "balance/internal/adapters/grpc"
+	"balance/internal/adapters/grpc/pb"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+type balanceStub struct {
+	income models.BalanceWithDesc
+}
+
+func (b *balanceStub) CreateAccount(ctx context.Context, tenant string,
+	account models.Account) (models.Account, error) {
+	return account, nil
+}
+
+func (b *balanceStub) GetAccount(ctx context.Context, tenant string, id int64) (models.Account, error) {
+	return models.Account{Id: id, Type: models.AccountUser}, nil
+}
+
+func (b *balanceStub) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	b.income = income
+	return nil
+}
+
+func (b *balanceStub) AddExpense(ctx context.Context, tenant string, expense models.BalanceWithDesc) error {
+	return fmt.Errorf("user_id %d: %w", expense.UserId, e.NotEnoughUserBalanceError)
+}
+
+func (b *balanceStub) AddIncomeTx(ctx context.Context, tx ports.Tx, tenant string,
+	income models.BalanceWithDesc) error {
+	return b.AddIncome(ctx, tenant, income)
+}
+
+func (b *balanceStub) AddExpenseTx(ctx context.Context, tx ports.Tx, tenant string,
+	expense models.BalanceWithDesc) error {
+	return b.AddExpense(ctx, tenant, expense)
+}
+
+func (b *balanceStub) DoTransfer(ctx context.Context, tenant string, transaction models.Transaction) error {
+	if transaction.UserIdFrom == 3 {
+		return fmt.Errorf("user_id %d: %w", transaction.UserIdFrom, e.AccountFrozenError)
+	}
+	return e.DatabaseError
+}
+
+func (b *balanceStub) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	if userId != 1 {
+		return models.Balance{}, fmt.Errorf("user_id %d: %w", userId, e.UnknownUserIdError)
+	}
+	return models.Balance{UserId: userId, Value: decimal.RequireFromString("10.55")}, nil
+}
+
+func (b *balanceStub) GetStatement(ctx context.Context, tenant string, userId int64, from, to time.Time,
+	w ports.StatementWriter) error {
+	return nil
+}
+
+func (b *balanceStub) GetHistory(ctx context.Context, tenant string, userId int64,
+	from, to time.Time) ([]models.Transaction, error) {
+	if userId != 1 {
+		return nil, fmt.Errorf("user_id %d: %w", userId, e.UnknownUserIdError)
+	}
+	return []models.Transaction{{Id: 1, UserIdTo: userId, Value: decimal.RequireFromString("10.55"),
+		Time: time.Now(), Description: "salary"}}, nil
+}
+
+func (b *balanceStub) SetFrozen(ctx context.Context, tenant string, userId int64, frozen bool) error {
+	return nil
+}
+
+func (b *balanceStub) Reconcile(ctx context.Context, tenant string) (models.Reconciliation, error) {
+	return models.Reconciliation{Mismatches: []models.ReconciliationMismatch{}}, nil
+}
+
+func TestGrpcBalance(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewProduction()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	stub := &balanceStub{}
+	server := grpcadapter.New(stub, nil, nil, logger.Sugar())
+	go server.Start(port)
+	defer server.Stop(ctx)
+
+	dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, "127.0.0.1:"+port,
+		grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewBalanceClient(conn)
+
+	_, err = client.AddIncome(ctx, &pb.AddIncomeRequest{UserId: 1, Value: "10.55", Description: "salary"})
+	require.NoError(t, err)
+	require.True(t, stub.income.Value.Equal(decimal.RequireFromString("10.55")))
+
+	balance, err := client.GetBalance(ctx, &pb.GetBalanceRequest{UserId: 1})
+	require.NoError(t, err)
+	require.Equal(t, "10.55", balance.GetValue())
+
+	_, err = client.AddIncome(ctx, &pb.AddIncomeRequest{UserId: 1, Value: "ten"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetBalance(ctx, &pb.GetBalanceRequest{UserId: 2})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.AddExpense(ctx, &pb.AddExpenseRequest{UserId: 1, Value: "100"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.DoTransfer(ctx, &pb.DoTransferRequest{UserIdFrom: 1, UserIdTo: 2, Value: "1"})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = client.DoTransfer(ctx, &pb.DoTransferRequest{UserIdFrom: 3, UserIdTo: 1, Value: "1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
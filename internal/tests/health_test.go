@@ -0,0 +1,144 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/publisher"
+	"balance/internal/domain/health"
+	"balance/internal/domain/models"
+	"balance/internal/domain/outbox"
+	"balance/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type healthStorageStub struct {
+	pingErr error
+	version int64
+}
+
+func (h *healthStorageStub) Ping(ctx context.Context) error {
+	return h.pingErr
+}
+
+func (h *healthStorageStub) MigrationVersion(ctx context.Context) (int64, error) {
+	return h.version, nil
+}
+
+type outboxStorageStub struct {
+	err error
+}
+
+func (o outboxStorageStub) ProcessEvents(ctx context.Context, limit int, fn func(models.Event) error) (int, error) {
+	return 0, o.err
+}
+
+func (o outboxStorageStub) GetOutboxStats(ctx context.Context) (models.OutboxStats, error) {
+	return models.OutboxStats{}, nil
+}
+
+func TestReadiness(t *testing.T) {
+	expected, err := utils.LatestMigrationVersion("../../db/changelog/")
+	require.NoError(t, err)
+	require.Positive(t, expected)
+
+	logger, _ := zap.NewProduction()
+	// failed deliveries are retried and do not make the service unready
+	relay := outbox.New(outboxStorageStub{err: errors.New("webhook unavailable")}, publisher.NewMemory(),
+		logger.Sugar(), 10*time.Millisecond, 10)
+	require.Error(t, relay.Check(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+	require.Eventually(t, func() bool {
+		return relay.Check(context.Background()) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	storage := &healthStorageStub{version: expected}
+	healthS := health.New(time.Second)
+	healthS.Register("postgres", health.DatabaseCheck(storage))
+	healthS.Register("migrations", health.MigrationCheck(storage, expected))
+	healthS.Register("outbox_relay", relay.Check)
+
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, nil, nil, httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+	server.WithHealth(healthS)
+	handler := server.Handler()
+
+	ready := func() (int, models.Health) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report models.Health
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec.Code, report
+	}
+
+	status, report := ready()
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, models.HealthOk, report.Status)
+	require.Len(t, report.Components, 3)
+
+	storage.pingErr = errors.New("connection refused")
+	storage.version = expected - 1
+	status, report = ready()
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, models.HealthFail, report.Components["postgres"].Status)
+	require.Equal(t, "connection refused", report.Components["postgres"].Error)
+	require.Equal(t, models.HealthFail, report.Components["migrations"].Status)
+	require.Equal(t, models.HealthOk, report.Components["outbox_relay"].Status)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		return relay.Check(context.Background()) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+type pendingOutboxStub struct {
+	outboxStorageStub
+}
+
+func (o pendingOutboxStub) ProcessEvents(ctx context.Context, limit int, fn func(models.Event) error) (int, error) {
+	return 1, fn(models.Event{})
+}
+
+type blockingPublisher struct {
+	started chan struct{}
+}
+
+func (p blockingPublisher) Publish(ctx context.Context, event models.Event) error {
+	close(p.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRelayStopTimeout(t *testing.T) {
+	blocking := blockingPublisher{started: make(chan struct{})}
+	relay := outbox.New(pendingOutboxStub{}, blocking, zap.NewNop().Sugar(), time.Hour, 10)
+	relay.WithStopTimeout(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	<-blocking.started
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not cancel the delivery after the stop timeout")
+	}
+}
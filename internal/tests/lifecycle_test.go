@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"balance/internal/application"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestLifecycleStopsInReverseOrder(t *testing.T) {
+	lifecycle := application.NewLifecycle(zap.NewNop().Sugar())
+
+	var stopped []string
+	for _, name := range []string{"db", "relay", "http"} {
+		name := name
+		lifecycle.OnStop(name, time.Second, func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+
+	require.NoError(t, lifecycle.Stop())
+	require.Equal(t, []string{"http", "relay", "db"}, stopped)
+
+	require.NoError(t, lifecycle.Stop())
+	require.Len(t, stopped, 3)
+}
+
+func TestLifecycleStopTimeout(t *testing.T) {
+	lifecycle := application.NewLifecycle(zap.NewNop().Sugar())
+
+	dbClosed := false
+	lifecycle.OnStop("db", time.Second, func(ctx context.Context) error {
+		dbClosed = true
+		return nil
+	})
+	lifecycle.OnStop("relay", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := lifecycle.Stop()
+	require.EqualError(t, err, "stop failed for: relay")
+	require.True(t, dbClosed)
+}
+
+func TestLifecycleReportsRunError(t *testing.T) {
+	lifecycle := application.NewLifecycle(zap.NewNop().Sugar())
+
+	lifecycle.Go("http server", func() error {
+		return errors.New("address already in use")
+	})
+
+	select {
+	case err := <-lifecycle.Err():
+		require.EqualError(t, err, "http server failed: address already in use")
+	case <-time.After(time.Second):
+		t.Fatal("run error was not reported")
+	}
+}
+
+func TestStopNotStartedApp(t *testing.T) {
+	require.NoError(t, application.Stop(&application.App{}))
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/domain/models"
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type blockingBalance struct {
+	balanceStub
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBalance) GetBalance(ctx context.Context, tenant string, userId int64) (models.Balance, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return b.balanceStub.GetBalance(ctx, tenant, userId)
+}
+
+func serve(handler http.Handler, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, nil, nil, httpadapter.Limits{
+		Rate:       0.5,
+		Burst:      2,
+		RouteRates: map[string]float64{"/balance/v1/statements": 0},
+	}, logger.Sugar())
+	require.NoError(t, err)
+	handler := server.Handler()
+
+	require.Equal(t, http.StatusOK, serve(handler, "/balance/v1/balance?user_id=1").Code)
+	require.Equal(t, http.StatusOK, serve(handler, "/balance/v1/balance?user_id=1").Code)
+
+	rec := serve(handler, "/balance/v1/balance?user_id=1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+	require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, serve(handler, "/balance/v1/statements?user_id=1&format=csv").Code)
+	}
+	require.Equal(t, http.StatusOK, serve(handler, "/balance/v1/webhooks").Code)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	balance := &blockingBalance{started: make(chan struct{}), release: make(chan struct{})}
+	server, err := httpadapter.New(balance, &webhookStub{}, nil, nil, httpadapter.Limits{MaxInFlight: 1},
+		logger.Sugar())
+	require.NoError(t, err)
+	handler := server.Handler()
+
+	done := make(chan int)
+	go func() {
+		done <- serve(handler, "/balance/v1/balance?user_id=1").Code
+	}()
+	select {
+	case <-balance.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not start")
+	}
+
+	rec := serve(handler, "/balance/v1/webhooks")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	close(balance.release)
+	require.Equal(t, http.StatusOK, <-done)
+	require.Equal(t, http.StatusOK, serve(handler, "/balance/v1/webhooks").Code)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/domain/models"
+	"balance/internal/utils"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type loggingBalance struct {
+	balanceStub
+	fallback *zap.SugaredLogger
+}
+
+func (b *loggingBalance) AddIncome(ctx context.Context, tenant string, income models.BalanceWithDesc) error {
+	utils.Logger(ctx, b.fallback).Infow("add income", "user_id", income.UserId, "description", income.Description)
+	return nil
+}
+
+func TestRequestLogging(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(utils.RedactCore(core)).Sugar()
+
+	server, err := httpadapter.New(&loggingBalance{fallback: logger}, &webhookStub{}, nil, nil,
+		httpadapter.Limits{}, logger)
+	require.NoError(t, err)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/balance/v1/income",
+		strings.NewReader(`{"user_id": 7, "value": 10, "description": "rent for flat 12"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "req-123", rec.Header().Get("X-Request-ID"))
+
+	serviceLogs := logs.FilterMessage("add income").AllUntimed()
+	require.Len(t, serviceLogs, 1)
+	require.Equal(t, "req-123", serviceLogs[0].ContextMap()["request_id"])
+	require.Equal(t, "[REDACTED]", serviceLogs[0].ContextMap()["description"])
+
+	accessLogs := logs.FilterMessage("request").AllUntimed()
+	require.Len(t, accessLogs, 1)
+	fields := accessLogs[0].ContextMap()
+	require.Equal(t, "req-123", fields["request_id"])
+	require.Equal(t, "/balance/v1/income", fields["route"])
+	require.Equal(t, int64(200), fields["status"])
+	require.Equal(t, int64(7), fields["user_id"])
+	require.Contains(t, fields, "latency")
+	for _, entry := range logs.AllUntimed() {
+		for _, value := range entry.ContextMap() {
+			require.NotContains(t, fmt.Sprint(value), "rent for flat")
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/balance/v1/balance?user_id=1", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Len(t, rec.Header().Get("X-Request-ID"), 32)
+}
+
+func TestRedactKeepsSampling(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(utils.RedactCore(zapcore.NewSamplerWithOptions(core, time.Minute, 1, 0))).Sugar()
+
+	for i := 0; i < 3; i++ {
+		logger.Infow("income", "description", "rent")
+	}
+	logger.Debugw("debug")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	require.Equal(t, "[REDACTED]", entries[0].ContextMap()["description"])
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/memory"
+	"balance/internal/domain/balance"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"balance/internal/ports"
+	"balance/internal/tests/conformance"
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryBalance(t *testing.T) {
+	ctx := context.Background()
+	service := balance.New(memory.New(), models.Currency{Code: "RUB", Scale: 2}, zap.NewNop().Sugar())
+
+	require.NoError(t, service.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.RequireFromString("10.55"), Description: "salary", Time: time.Now(),
+	}))
+	require.NoError(t, service.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.RequireFromString("5.15"), Description: "cinema", Time: time.Now(),
+	}))
+	require.NoError(t, service.DoTransfer(ctx, models.DefaultTenant, models.Transaction{
+		UserIdFrom: 1, UserIdTo: 2, Value: decimal.RequireFromString("5.40"), Time: time.Now(),
+	}))
+
+	from, err := service.GetBalance(ctx, models.DefaultTenant, 1)
+	require.NoError(t, err)
+	require.True(t, from.Value.IsZero())
+	to, err := service.GetBalance(ctx, models.DefaultTenant, 2)
+	require.NoError(t, err)
+	require.Equal(t, "5.4", to.Value.String())
+
+	err = service.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 3,
+		Value: decimal.NewFromInt(1), Time: time.Now()})
+	require.ErrorIs(t, err, e.UnknownUserIdError)
+
+	err = service.DoTransfer(ctx, models.DefaultTenant, models.Transaction{
+		UserIdFrom: 2, UserIdTo: 1, Value: decimal.NewFromInt(6), Time: time.Now(),
+	})
+	require.ErrorIs(t, err, e.NotEnoughUserBalanceError)
+
+	_, err = service.GetBalance(ctx, models.DefaultTenant, 3)
+	require.ErrorIs(t, err, e.UnknownUserIdError)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.New()
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, storage.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(100), Time: start.Add(2 * time.Hour), Description: "second",
+	}))
+	require.NoError(t, storage.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(50), Time: start.Add(time.Hour), Description: "first",
+	}))
+	// the failed expense must not be recorded
+	require.Error(t, storage.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(1000), Time: start.Add(3 * time.Hour),
+	}))
+
+	var descriptions []string
+	end := start.Add(24 * time.Hour)
+	err := storage.GetHistory(ctx, models.DefaultTenant, 1, start, end, func(transaction models.Transaction) error {
+		descriptions = append(descriptions, transaction.Description)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, descriptions)
+
+	at, err := storage.GetBalanceAt(ctx, models.DefaultTenant, 1, start.Add(90*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, "50", at.String())
+}
+
+func TestMemoryConcurrentTransfers(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.New()
+	require.NoError(t, storage.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 1,
+		Value: decimal.NewFromInt(100)}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			storage.DoTransfer(ctx, models.DefaultTenant, models.Transaction{UserIdFrom: 1, UserIdTo: 2,
+				Value: decimal.NewFromInt(1)})
+		}()
+	}
+	wg.Wait()
+
+	from, err := storage.GetBalance(ctx, models.DefaultTenant, 1)
+	require.NoError(t, err)
+	require.True(t, from.Value.IsZero())
+	to, err := storage.GetBalance(ctx, models.DefaultTenant, 2)
+	require.NoError(t, err)
+	require.Equal(t, "100", to.Value.String())
+}
+
+func TestMemoryConformance(t *testing.T) {
+	conformance.BalanceStorage(t, func(t *testing.T) ports.BalanceStoragePort {
+		return memory.New()
+	})
+}
+
+func TestStatementIncludesToDate(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop().Sugar()
+	storage := memory.New()
+	day := time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, storage.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(100), Time: day.Add(-time.Hour), Description: "salary",
+	}))
+	require.NoError(t, storage.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(30), Time: day.Add(18 * time.Hour), Description: "dinner",
+	}))
+	require.NoError(t, storage.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{
+		UserId: 1, Value: decimal.NewFromInt(20), Time: day.AddDate(0, 0, 1), Description: "breakfast",
+	}))
+
+	service := balance.New(storage, models.Currency{Code: "RUB", Scale: 2}, logger)
+	server, err := httpadapter.New(service, nil, nil, nil, httpadapter.Limits{}, logger)
+	require.NoError(t, err)
+
+	// a plain to date includes the transactions of that day
+	rec := serve(server.Handler(), "/balance/v1/statements?user_id=1&from=2022-10-20&to=2022-10-20&format=csv")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "dinner")
+	require.NotContains(t, rec.Body.String(), "salary")
+	require.NotContains(t, rec.Body.String(), "breakfast")
+
+	rec = serve(server.Handler(), "/balance/v1/history?user_id=1&from=2022-10-20&to=2022-10-20")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "dinner")
+	require.NotContains(t, rec.Body.String(), "breakfast")
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/metrics"
+	"balance/internal/domain/auth"
+	"balance/internal/domain/models"
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type poolStatsStub struct{}
+
+func (p poolStatsStub) PoolStats() models.PoolStats {
+	return models.PoolStats{AcquireCount: 7, AcquireDuration: 2 * time.Second, AcquiredConns: 1, MaxConns: 4}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	m := metrics.New()
+	m.Register(metrics.NewPoolCollector(poolStatsStub{}))
+
+	server, err := httpadapter.New(m.Balance(&balanceStub{}), &webhookStub{}, nil, nil, httpadapter.Limits{},
+		logger.Sugar())
+	require.NoError(t, err)
+	server.WithMetrics(m.Middleware, m.Handler())
+	handler := server.Handler()
+
+	requests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10.55}`},
+		{http.MethodPost, "/balance/v1/expense", `{"user_id": 1, "value": 5}`},
+		{http.MethodGet, "/balance/v1/balance?user_id=1", ""},
+		{http.MethodGet, "/balance/v1/balance?user_id=2", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.target, strings.NewReader(r.body))
+		if r.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`balance_http_requests_total{method="GET",route="/balance/v1/balance",status="200"} 1`,
+		`balance_http_requests_total{method="GET",route="/balance/v1/balance",status="404"} 1`,
+		`balance_http_request_duration_seconds_count{method="POST",route="/balance/v1/income",status="200"} 1`,
+		`balance_operations_total{result="success",type="income"} 1`,
+		`balance_operation_amount_total{type="income"} 10.55`,
+		`balance_domain_errors_total{code="insufficient_balance",operation="expense"} 1`,
+		`balance_domain_errors_total{code="unknown_user_id",operation="balance"} 1`,
+		`balance_pgxpool_acquires_total 7`,
+		`balance_pgxpool_acquire_duration_seconds_total 2`,
+	} {
+		require.Contains(t, body, line)
+	}
+}
+
+func TestMetricsRequireAdmin(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	m := metrics.New()
+	authS := auth.New(&clientStorage{clients: map[string]models.Client{}}, logger)
+	_, adminKey, err := authS.CreateClient(context.Background(), "", "prometheus", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+	_, incomeKey, err := authS.CreateClient(context.Background(), "", "billing", []string{models.ScopeIncome})
+	require.NoError(t, err)
+
+	server, err := httpadapter.New(&balanceStub{}, nil, authS, nil, httpadapter.Limits{}, logger)
+	require.NoError(t, err)
+	server.WithMetrics(m.Middleware, m.Handler())
+	handler := server.Handler()
+
+	for apiKey, status := range map[string]int{
+		"":        http.StatusUnauthorized,
+		incomeKey: http.StatusForbidden,
+		adminKey:  http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, status, rec.Code, "api key %q", apiKey)
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/domain/models"
+	"bytes"
+	"context"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type webhookStub struct{}
+
+func (w *webhookStub) Subscribe(ctx context.Context, tenant string,
+	subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	subscription.Id = 1
+	subscription.Secret = "secret"
+	subscription.CreatedAt = time.Now()
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []models.EventType{}
+	}
+	return subscription, nil
+}
+
+func (w *webhookStub) ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error) {
+	return []models.WebhookSubscription{{Id: 1, Url: "http://localhost/hook", EventTypes: []models.EventType{},
+		CreatedAt: time.Now()}}, nil
+}
+
+func (w *webhookStub) Unsubscribe(ctx context.Context, tenant string, id int64) error {
+	return nil
+}
+
+func (w *webhookStub) ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error) {
+	return nil, nil
+}
+
+func (w *webhookStub) Replay(ctx context.Context, tenant string, deadLetterId int64) error {
+	return nil
+}
+
+func newTestServer(t *testing.T) *httpadapter.Server {
+	logger, _ := zap.NewProduction()
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, nil, nil, httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+	return server
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	spec, _, err := httpadapter.LoadOpenAPI()
+	require.NoError(t, err)
+
+	var specRoutes []string
+	for path, item := range spec.Paths {
+		for method := range item.Operations() {
+			specRoutes = append(specRoutes, method+" "+spec.Servers[0].URL+path)
+		}
+	}
+
+	var routerRoutes []string
+	handler := newTestServer(t).Handler().(chi.Routes)
+	err = chi.Walk(handler, func(method string, route string, handler http.Handler,
+		middlewares ...func(http.Handler) http.Handler) error {
+		route = strings.ReplaceAll(route, "/*/", "/")
+		route = strings.TrimSuffix(route, "/")
+		if strings.HasPrefix(route, "/balance/v1/") {
+			routerRoutes = append(routerRoutes, method+" "+route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	sort.Strings(specRoutes)
+	sort.Strings(routerRoutes)
+	require.Equal(t, specRoutes, routerRoutes)
+}
+
+func TestOpenAPIValidatesTraffic(t *testing.T) {
+	_, router, err := httpadapter.LoadOpenAPI()
+	require.NoError(t, err)
+	openapi3filter.RegisterBodyDecoder("text/csv", openapi3filter.FileBodyDecoder)
+	handler := newTestServer(t).Handler()
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10.55, "description": "salary"}`, 200},
+		{http.MethodPost, "/balance/v1/income", `{"value": 10.55}`, 400},
+		{http.MethodPost, "/balance/v1/expense", `{"user_id": 1, "value": "5.15"}`, 422},
+		{http.MethodPost, "/balance/v1/transfer", `{"user_id_from": 1, "user_id_to": 2, "value": 5}`, 500},
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", 200},
+		{http.MethodGet, "/balance/v1/balance?user_id=2", "", 404},
+		{http.MethodGet, "/balance/v1/balance?user_id=abc", "", 400},
+		{http.MethodGet, "/balance/v1/statements?user_id=1&format=csv", "", 200},
+		{http.MethodGet, "/balance/v1/statements?user_id=1&format=pdf", "", 400},
+		{http.MethodGet, "/balance/v1/history?user_id=1&from=2022-10-01", "", 200},
+		{http.MethodGet, "/balance/v1/history?user_id=2", "", 404},
+		{http.MethodGet, "/balance/v1/webhooks", "", 200},
+		{http.MethodPost, "/balance/v1/webhooks", `{"url": "http://localhost/hook"}`, 201},
+		{http.MethodDelete, "/balance/v1/webhooks/1", "", 200},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		if c.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, c.status, rec.Code, "%s %s: %s", c.method, c.target, rec.Body.String())
+
+		validationReq := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		route, pathParams, err := router.FindRoute(validationReq)
+		require.NoError(t, err)
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    validationReq,
+				PathParams: pathParams,
+				Route:      route,
+			},
+			Status:  rec.Code,
+			Header:  rec.Header(),
+			Body:    ioutil.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+			Options: &openapi3filter.Options{IncludeResponseStatus: true},
+		})
+		require.NoError(t, err, "%s %s", c.method, c.target)
+	}
+}
+
+func TestUnknownFieldsRejected(t *testing.T) {
+	handler := newTestServer(t).Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/balance/v1/income",
+		strings.NewReader(`{"user_id": 1, "value": 10, "currency": "USD"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/memory"
+	"balance/internal/domain/auth"
+	"balance/internal/domain/balance"
+	"balance/internal/domain/models"
+	"balance/internal/domain/tenant"
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	tenants := tenant.NewRegistry(models.TenantSettings{Currency: models.Currency{Code: "RUB", Scale: 2}},
+		map[string]models.TenantSettings{
+			"acme": {Currency: models.Currency{Code: "USD", Scale: 2}, MaxOperation: decimal.NewFromInt(100)},
+		})
+	balanceS := balance.New(memory.New(), models.Currency{Code: "RUB", Scale: 2}, logger)
+	balanceS.WithTenants(tenants)
+	authS := auth.New(&clientStorage{clients: map[string]models.Client{}}, logger)
+
+	_, platformKey, err := authS.CreateClient(context.Background(), "", "platform", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+	_, acmeKey, err := authS.CreateClient(context.Background(), "acme", "acme", []string{models.ScopeAdmin})
+	require.NoError(t, err)
+
+	server, err := httpadapter.New(balanceS, nil, authS, nil, httpadapter.Limits{}, logger)
+	require.NoError(t, err)
+	server.WithTenants(tenants)
+	handler := server.Handler()
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		apiKey string
+		tenant string
+		status int
+	}{
+		{"platform income", http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 50}`, platformKey,
+			"", http.StatusOK},
+		{"acme income", http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 5}`, platformKey,
+			"acme", http.StatusOK},
+		{"bound client", http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 5}`, acmeKey,
+			"", http.StatusOK},
+		{"over tenant limit", http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 101}`, acmeKey,
+			"", http.StatusBadRequest},
+		{"other tenant", http.MethodGet, "/balance/v1/balance?user_id=1", "", acmeKey,
+			models.DefaultTenant, http.StatusForbidden},
+		{"unknown tenant", http.MethodGet, "/balance/v1/balance?user_id=1", "", platformKey,
+			"unknown", http.StatusBadRequest},
+		{"separate accounts", http.MethodPost, "/balance/v1/expense", `{"user_id": 1, "value": 11}`, acmeKey,
+			"", http.StatusUnprocessableEntity},
+		{"bound client creates clients", http.MethodPost, "/admin/v1/clients",
+			`{"name": "billing", "scopes": ["income"]}`, acmeKey, "", http.StatusCreated},
+		{"bound client escapes tenant", http.MethodPost, "/admin/v1/clients",
+			`{"name": "billing", "tenant": "default", "scopes": ["income"]}`, acmeKey, "", http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		if c.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("X-API-Key", c.apiKey)
+		if c.tenant != "" {
+			req.Header.Set("X-Tenant-Id", c.tenant)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, c.status, rec.Code, "%s: %s", c.name, rec.Body.String())
+	}
+
+	ctx := context.Background()
+	platform, err := balanceS.GetBalance(ctx, models.DefaultTenant, 1)
+	require.NoError(t, err)
+	require.Equal(t, "50", platform.Value.String())
+	acme, err := balanceS.GetBalance(ctx, "acme", 1)
+	require.NoError(t, err)
+	require.Equal(t, "10", acme.Value.String())
+
+	clients, err := authS.ListClients(auth.WithClient(ctx, models.Client{Tenant: "acme",
+		Scopes: []string{models.ScopeAdmin}}), "")
+	require.NoError(t, err)
+	require.Len(t, clients, 2)
+	for _, client := range clients {
+		require.Equal(t, "acme", client.Tenant)
+	}
+}
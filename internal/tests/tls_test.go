@@ -0,0 +1,151 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/postgres"
+	"balance/internal/utils"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, serial int64, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "balance-test-" + strconv.FormatInt(serial, 10)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string, modTime time.Time) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certFile, c.pem, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func freePort(t *testing.T) string {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listen.Close()
+	return strconv.Itoa(listen.Addr().(*net.TCPAddr).Port)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"),
+		filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, 1, nil, true)
+	require.NoError(t, ioutil.WriteFile(caFile, ca.pem, 0600))
+	newTestCert(t, 2, ca, false).write(t, certFile, keyFile, time.Now().Add(-time.Minute))
+	client := newTestCert(t, 3, ca, false)
+	otherClient := newTestCert(t, 4, newTestCert(t, 5, nil, true), false)
+
+	certs, err := utils.NewCertReloader(certFile, keyFile, caFile, tls.RequireAndVerifyClientCert)
+	require.NoError(t, err)
+
+	logger, _ := zap.NewProduction()
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, nil, nil, httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+	port := freePort(t)
+	go server.Start(port, certs.TLSConfig())
+	defer server.Stop(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(cert *testCert) (*http.Response, error) {
+		config := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			config.Certificates = []tls.Certificate{cert.tlsCertificate()}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		return httpClient.Get("https://127.0.0.1:" + port + "/health")
+	}
+
+	require.Eventually(t, func() bool {
+		resp, err := get(client)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = get(nil)
+	require.Error(t, err)
+	_, err = get(otherClient)
+	require.Error(t, err)
+
+	newTestCert(t, 6, ca, false).write(t, certFile, keyFile, time.Now())
+	resp, err := get(client)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int64(6), resp.TLS.PeerCertificates[0].SerialNumber.Int64())
+}
+
+func TestPostgresConnString(t *testing.T) {
+	connString := postgres.Options{
+		User:        "app",
+		Password:    "p@ss word",
+		Host:        "db",
+		Port:        "5432",
+		Database:    "app",
+		SslMode:     "verify-full",
+		SslRootCert: "/certs/ca.crt",
+	}.ConnString()
+	require.Equal(t,
+		"postgresql://app:p%40ss%20word@db:5432/app?sslmode=verify-full&sslrootcert=%2Fcerts%2Fca.crt",
+		connString)
+
+	require.Contains(t, postgres.Options{Host: "db", Port: "5432"}.ConnString(), "sslmode=disable")
+}
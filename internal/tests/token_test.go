@@ -0,0 +1,160 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/domain/auth"
+	"balance/internal/domain/balance"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	data, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, subject string, expiresAt time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    "bff",
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestUserTokenVerification(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier, err := auth.NewTokenVerifier(writeJWKS(t, "test", &key.PublicKey), "bff", "")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	userId, tenant, err := verifier.VerifyToken(ctx, signToken(t, "test", key, "42", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, int64(42), userId)
+	require.Equal(t, models.DefaultTenant, tenant)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "7", "iss": "bff", "exp": time.Now().Add(time.Hour).Unix(), "tenant": "acme",
+	})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	userId, tenant, err = verifier.VerifyToken(ctx, signed)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), userId)
+	require.Equal(t, "acme", tenant)
+
+	_, _, err = verifier.VerifyToken(ctx, signToken(t, "test", key, "42", time.Now().Add(-time.Hour)))
+	require.ErrorIs(t, err, e.UnauthenticatedError)
+	_, _, err = verifier.VerifyToken(ctx, signToken(t, "test", otherKey, "42", time.Now().Add(time.Hour)))
+	require.ErrorIs(t, err, e.UnauthenticatedError)
+	_, _, err = verifier.VerifyToken(ctx, signToken(t, "test", key, "admin", time.Now().Add(time.Hour)))
+	require.ErrorIs(t, err, e.UnauthenticatedError)
+
+	token = jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "7", "iss": "bff"})
+	token.Header["kid"] = "test"
+	signed, err = token.SignedString(key)
+	require.NoError(t, err)
+	_, _, err = verifier.VerifyToken(ctx, signed)
+	require.ErrorIs(t, err, e.UnauthenticatedError)
+}
+
+func TestUserOwnsBalance(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	service := balance.New(nil, models.Currency{Code: "RUB", Scale: 2}, logger.Sugar())
+	ctx := auth.WithUser(context.Background(), 1)
+
+	_, err := service.GetBalance(ctx, models.DefaultTenant, 2)
+	require.ErrorIs(t, err, e.ForbiddenError)
+	err = service.GetStatement(ctx, models.DefaultTenant, 2, time.Time{}, time.Now(), nil)
+	require.ErrorIs(t, err, e.ForbiddenError)
+	err = service.DoTransfer(ctx, models.DefaultTenant, models.Transaction{UserIdFrom: 2, UserIdTo: 1,
+		Value: decimal.NewFromInt(1)})
+	require.ErrorIs(t, err, e.ForbiddenError)
+	err = service.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 1, Value: decimal.NewFromInt(1)})
+	require.ErrorIs(t, err, e.ForbiddenError)
+}
+
+func TestUserTokenScopes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier, err := auth.NewTokenVerifier(writeJWKS(t, "test", &key.PublicKey), "", "")
+	require.NoError(t, err)
+
+	logger, _ := zap.NewProduction()
+	authS := auth.New(&clientStorage{clients: map[string]models.Client{}}, logger.Sugar())
+	_, serviceKey, err := authS.CreateClient(context.Background(), "", "billing", []string{models.ScopeIncome})
+	require.NoError(t, err)
+
+	server, err := httpadapter.New(&balanceStub{}, &webhookStub{}, authS, verifier, httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+	handler := server.Handler()
+	token := "Bearer " + signToken(t, "test", key, "1", time.Now().Add(time.Hour))
+
+	cases := []struct {
+		method        string
+		target        string
+		body          string
+		apiKey        string
+		authorization string
+		status        int
+	}{
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", "", token, http.StatusOK},
+		{http.MethodGet, "/balance/v1/balance?user_id=1", "", "", "Bearer invalid", http.StatusUnauthorized},
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10}`, "", token, http.StatusForbidden},
+		{http.MethodGet, "/balance/v1/webhooks", "", "", token, http.StatusForbidden},
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10}`, serviceKey, "", http.StatusOK},
+		{http.MethodPost, "/balance/v1/income", `{"user_id": 1, "value": 10}`, "", "", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		if c.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, c.status, rec.Code, "%s %s: %s", c.method, c.target, rec.Body.String())
+	}
+}
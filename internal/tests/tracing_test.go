@@ -0,0 +1,97 @@
+package tests
+
+import (
+	httpadapter "balance/internal/adapters/http"
+	"balance/internal/adapters/tracing"
+	"balance/internal/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(provider)
+
+	var out bytes.Buffer
+	shutdown, err := tracing.New(context.Background(), tracing.Options{
+		Exporter:    tracing.ExporterStdout,
+		ServiceName: "balance-test",
+		Writer:      &out,
+	})
+	require.NoError(t, err)
+
+	logger, _ := zap.NewProduction()
+	server, err := httpadapter.New(tracing.Balance(&balanceStub{}), &webhookStub{}, nil, nil,
+		httpadapter.Limits{}, logger.Sugar())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/balance/v1/balance?user_id=1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, shutdown(context.Background()))
+
+	spans := map[string]exportedSpan{}
+	decoder := json.NewDecoder(&out)
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		spans[span.Name] = span
+	}
+
+	httpSpan, ok := spans["GET /balance/v1/balance"]
+	require.True(t, ok, "spans: %v", spans)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", httpSpan.SpanContext.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", httpSpan.Parent.SpanID)
+
+	serviceSpan, ok := spans["balance.Service/GetBalance"]
+	require.True(t, ok, "spans: %v", spans)
+	require.Equal(t, httpSpan.SpanContext.TraceID, serviceSpan.SpanContext.TraceID)
+	require.Equal(t, httpSpan.SpanContext.SpanID, serviceSpan.Parent.SpanID)
+}
+
+func TestLoggerWithTrace(t *testing.T) {
+	traceId, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanId, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId}))
+
+	core, logs := observer.New(zap.InfoLevel)
+	utils.LoggerWithTrace(ctx, zap.New(core).Sugar()).Info("transfer")
+	utils.LoggerWithTrace(context.Background(), zap.New(core).Sugar()).Info("relay")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entries[0].ContextMap()["trace_id"])
+	require.Equal(t, "00f067aa0ba902b7", entries[0].ContextMap()["span_id"])
+	require.NotContains(t, entries[1].ContextMap(), "trace_id")
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"balance/internal/domain/balance"
+	e "balance/internal/domain/errors"
+	"balance/internal/domain/models"
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+)
+
+func TestServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewProduction()
+	service := balance.New(nil, models.Currency{Code: "RUB", Scale: 2}, logger.Sugar())
+
+	fields := func(err error) []string {
+		var validationErr *e.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		require.True(t, errors.Is(err, e.ValidationFailedError))
+		var names []string
+		for _, field := range validationErr.Fields {
+			names = append(names, field.Field)
+		}
+		return names
+	}
+
+	err := service.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 1,
+		Value: decimal.NewFromInt(-100)})
+	require.Equal(t, []string{"value"}, fields(err))
+
+	err = service.AddExpense(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 0,
+		Value: decimal.RequireFromString("10.555"), Description: strings.Repeat("x", balance.MaxDescriptionLength+1)})
+	require.Equal(t, []string{"user_id", "value", "description"}, fields(err))
+
+	err = service.DoTransfer(ctx, models.DefaultTenant, models.Transaction{UserIdFrom: 1, UserIdTo: 1,
+		Value: decimal.Zero})
+	require.Equal(t, []string{"user_id_to", "value"}, fields(err))
+}
+
+func TestServiceValidationUsesCurrencyScale(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewProduction()
+
+	currency, err := models.CurrencyByCode("JPY")
+	require.NoError(t, err)
+	service := balance.New(nil, currency, logger.Sugar())
+
+	err = service.AddIncome(ctx, models.DefaultTenant, models.BalanceWithDesc{UserId: 1,
+		Value: decimal.RequireFromString("10.5")})
+	require.True(t, errors.Is(err, e.ValidationFailedError))
+}
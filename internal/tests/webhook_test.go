@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"balance/internal/domain/models"
+	"balance/internal/domain/webhook"
+	"context"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type webhookStorage struct {
+	mu             sync.Mutex
+	subscriptions  []models.WebhookSubscription
+	deliveries     []models.WebhookDelivery
+	lastDeliveryId int64
+	deadLetters    []models.DeadLetter
+}
+
+func (s *webhookStorage) CreateSubscription(ctx context.Context, tenant string,
+	subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription.Id = int64(len(s.subscriptions) + 1)
+	s.subscriptions = append(s.subscriptions, subscription)
+	return subscription, nil
+}
+
+func (s *webhookStorage) GetSubscription(ctx context.Context, tenant string,
+	id int64) (models.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscriptions[id-1], nil
+}
+
+func (s *webhookStorage) ListSubscriptions(ctx context.Context, tenant string) ([]models.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.WebhookSubscription{}, s.subscriptions...), nil
+}
+
+func (s *webhookStorage) DeleteSubscription(ctx context.Context, tenant string, id int64) error {
+	return nil
+}
+
+func (s *webhookStorage) AddDeliveries(ctx context.Context, tenant string,
+	deliveries []models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range deliveries {
+		if s.find(delivery.Subscription.Id, delivery.Event.Id) >= 0 {
+			continue
+		}
+		s.lastDeliveryId++
+		delivery.Id = s.lastDeliveryId
+		s.deliveries = append(s.deliveries, delivery)
+	}
+	return nil
+}
+
+func (s *webhookStorage) find(subscriptionId, eventId int64) int {
+	for i, delivery := range s.deliveries {
+		if delivery.Subscription.Id == subscriptionId && delivery.Event.Id == eventId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *webhookStorage) ClaimDeliveries(ctx context.Context, now, lease time.Time,
+	limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.WebhookDelivery
+	for i := range s.deliveries {
+		if len(claimed) < limit && !s.deliveries[i].NextAttemptAt.After(now) {
+			s.deliveries[i].NextAttemptAt = lease
+			claimed = append(claimed, s.deliveries[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (s *webhookStorage) RetryDelivery(ctx context.Context, tenant string, delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[s.find(delivery.Subscription.Id, delivery.Event.Id)] = delivery
+	return nil
+}
+
+func (s *webhookStorage) DeleteDelivery(ctx context.Context, tenant string, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delete(id)
+	return nil
+}
+
+func (s *webhookStorage) delete(id int64) {
+	for i, delivery := range s.deliveries {
+		if delivery.Id == id {
+			s.deliveries = append(s.deliveries[:i], s.deliveries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *webhookStorage) DeadLetterDelivery(ctx context.Context, tenant string, id int64,
+	deadLetter models.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delete(id)
+	deadLetter.Id = int64(len(s.deadLetters) + 1)
+	s.deadLetters = append(s.deadLetters, deadLetter)
+	return nil
+}
+
+func (s *webhookStorage) GetDeadLetter(ctx context.Context, tenant string, id int64) (models.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadLetters[id-1], nil
+}
+
+func (s *webhookStorage) ListDeadLetters(ctx context.Context, tenant string) ([]models.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.DeadLetter{}, s.deadLetters...), nil
+}
+
+func (s *webhookStorage) UpdateDeadLetter(ctx context.Context, tenant string, deadLetter models.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters[deadLetter.Id-1] = deadLetter
+	return nil
+}
+
+func creditedEvent(t *testing.T, id int64, userId int64) models.Event {
+	payload, err := json.Marshal(models.BalanceChangedPayload{UserId: userId, Value: decimal.NewFromInt(10)})
+	require.NoError(t, err)
+	return models.Event{Id: id, Type: models.BalanceCredited, Payload: payload, CreatedAt: time.Now()}
+}
+
+func TestWebhookSignedDelivery(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewProduction()
+
+	var received []models.Event
+	var mu sync.Mutex
+	secret := "top-secret"
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		signature := webhook.Sign(secret, r.Header.Get(webhook.TimestampHeader), body)
+		if r.Header.Get(webhook.SignatureHeader) != signature {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event models.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	service := webhook.New(&webhookStorage{}, receiver.Client(), logger.Sugar(), 3, time.Millisecond)
+
+	userId := int64(1)
+	_, err := service.Subscribe(ctx, models.DefaultTenant, models.WebhookSubscription{
+		Url:        receiver.URL,
+		Secret:     secret,
+		EventTypes: []models.EventType{models.BalanceCredited},
+		UserId:     &userId,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Publish(ctx, creditedEvent(t, 1, userId)))
+	require.NoError(t, service.Publish(ctx, creditedEvent(t, 2, userId+1)))
+	// the relay publishes an event again if its transaction did not commit
+	require.NoError(t, service.Publish(ctx, creditedEvent(t, 1, userId)))
+
+	attempted, err := service.SendDeliveries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, attempted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 1)
+	require.Equal(t, int64(1), received[0].Id)
+}
+
+func TestWebhookDeadLetterAndReplay(t *testing.T) {
+	ctx := context.Background()
+	logger, _ := zap.NewProduction()
+
+	var calls int32
+	var healthy int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	storage := &webhookStorage{}
+	service := webhook.New(storage, receiver.Client(), logger.Sugar(), 3, time.Millisecond)
+
+	_, err := service.Subscribe(ctx, models.DefaultTenant, models.WebhookSubscription{Url: receiver.URL})
+	require.NoError(t, err)
+
+	// publishing only queues the delivery, the worker sends it
+	require.NoError(t, service.Publish(ctx, creditedEvent(t, 1, 1)))
+	require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	var deadLetters []models.DeadLetter
+	require.Eventually(t, func() bool {
+		_, err = service.SendDeliveries(ctx)
+		require.NoError(t, err)
+		deadLetters, err = service.ListDeadLetters(ctx, models.DefaultTenant)
+		require.NoError(t, err)
+		return len(deadLetters) == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	require.Equal(t, 3, deadLetters[0].Attempts)
+	require.Nil(t, deadLetters[0].ReplayedAt)
+
+	// replay queues the event again instead of sending it in the request
+	atomic.StoreInt32(&healthy, 1)
+	require.NoError(t, service.Replay(ctx, models.DefaultTenant, deadLetters[0].Id))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	deadLetters, err = service.ListDeadLetters(ctx, models.DefaultTenant)
+	require.NoError(t, err)
+	require.NotNil(t, deadLetters[0].ReplayedAt)
+
+	attempted, err := service.SendDeliveries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, attempted)
+	require.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	deadLetters, err = service.ListDeadLetters(ctx, models.DefaultTenant)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var sensitiveKeys = map[string]bool{
+	"description":   true,
+	"api_key":       true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+}
+
+type loggerKey struct{}
+
+// NewLogger builds the application logger with the given level and json or
+// console encoding. Sensitive fields are redacted.
+func NewLogger(level, format string) (*zap.Logger, error) {
+	config := zap.NewProductionConfig()
+	if err := config.Level.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	config.Encoding = format
+	if format == "console" {
+		config.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	return config.Build(zap.WrapCore(RedactCore))
+}
+
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the request-scoped logger from ctx, or fallback when there is
+// none, with the trace and span ids of the current span attached.
+func Logger(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	logger := fallback
+	if scoped, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		logger = scoped
+	}
+	return LoggerWithTrace(ctx, logger)
+}
+
+// LoggerWithTrace adds the trace and span ids of the current span to logger.
+func LoggerWithTrace(ctx context.Context, logger *zap.SugaredLogger) *zap.SugaredLogger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+	return logger.With("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+}
+
+type redactingCore struct {
+	zapcore.Core
+}
+
+// RedactCore replaces the values of fields that may carry descriptions,
+// credentials or other PII, use it with zap.WrapCore.
+func RedactCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redact(fields))}
+}
+
+// Check lets the wrapped core decide, so its sampling and level checks still
+// apply, but registers the redacting core for the write instead of it.
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(entry, nil) != nil {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redact(fields))
+}
+
+func redact(fields []zapcore.Field) []zapcore.Field {
+	var result []zapcore.Field
+	for i, field := range fields {
+		if !sensitiveKeys[strings.ToLower(field.Key)] {
+			continue
+		}
+		if result == nil {
+			result = make([]zapcore.Field, len(fields))
+			copy(result, fields)
+		}
+		result[i] = zap.String(field.Key, redacted)
+	}
+	if result == nil {
+		return fields
+	}
+	return result
+}
@@ -16,3 +16,15 @@ func ApplyMigrations(connStr string, path string) error {
 	}
 	return nil
 }
+
+func LatestMigrationVersion(path string) (int64, error) {
+	migrations, err := goose.CollectMigrations(path, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
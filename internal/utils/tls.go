@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a TLS config that is rebuilt whenever the certificate,
+// key or client CA files change on disk, so rotated certificates are picked
+// up by new connections without a restart.
+type CertReloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+
+	mu       sync.Mutex
+	config   *tls.Config
+	modTimes []time.Time
+}
+
+func NewCertReloader(certFile, keyFile, caFile string, clientAuth tls.ClientAuthType) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		clientAuth: clientAuth,
+	}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.getConfigForClient,
+	}
+}
+
+func (r *CertReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err == nil && r.changed(modTimes) {
+		// A failed reload usually means the files are mid-rotation, keep
+		// serving the previous certificate until they are consistent.
+		_ = r.load(modTimes)
+	}
+	return r.config, nil
+}
+
+func (r *CertReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
+
+func (r *CertReloader) stat() ([]time.Time, error) {
+	files := r.files()
+	modTimes := make([]time.Time, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s failed: %w", file, err)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func (r *CertReloader) changed(modTimes []time.Time) bool {
+	for i := range modTimes {
+		if !modTimes[i].Equal(r.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CertReloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate failed: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.caFile != "" {
+		caPEM, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("read client ca failed: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("client ca %s has no certificates", r.caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = r.clientAuth
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}